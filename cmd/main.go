@@ -23,28 +23,71 @@ func main() {
 
 	logger := slog.New(handler)
 
-	votingRepo, err := repository.NewTarantoolClient(cfg.TarantoolHost, cfg.TarantoolPort, cfg.TarantoolUser, cfg.TarantoolPassword)
-	if err != nil {
-		logger.Error("Ошибка создания подключения к базе данных", slog.Any("error", err))
-		return
+	var votingRepo repository.VotingRepository
+	switch cfg.Storage {
+	case config.StorageMemory:
+		logger.Warn("Используется хранилище в памяти, данные будут потеряны при перезапуске")
+		votingRepo = repository.NewMemoryRepository()
+	case config.StorageSQLite, config.StoragePostgres:
+		sqlRepo, err := repository.NewSQLRepository(cfg.Storage, cfg.SQLDSN, logger)
+		if err != nil {
+			logger.Error("Ошибка создания подключения к базе данных", slog.Any("error", err))
+			return
+		}
+		if err := sqlRepo.Migrate(); err != nil {
+			logger.Error("Ошибка миграции схемы базы данных", slog.Any("error", err))
+			return
+		}
+		votingRepo = sqlRepo
+	default:
+		tarantoolRepo, err := repository.NewTarantoolClient(cfg.TarantoolHost, cfg.TarantoolPort, cfg.TarantoolUser, cfg.TarantoolPassword, logger)
+		if err != nil {
+			logger.Error("Ошибка создания подключения к базе данных", slog.Any("error", err))
+			return
+		}
+		if err := tarantoolRepo.Migrate(); err != nil {
+			logger.Error("Ошибка миграции схемы базы данных", slog.Any("error", err))
+			return
+		}
+		votingRepo = tarantoolRepo
 	}
+
 	votingService := &service.VotingService{
-		Client:   model.NewAPIv4Client(cfg.MattermostURL),
-		VoteRepo: votingRepo,
-		Logger:   logger,
+		Client:       model.NewAPIv4Client(cfg.MattermostURL),
+		VoteRepo:     votingRepo,
+		BallotSecret: []byte(cfg.BallotSecret),
+		Logger:       logger,
 	}
 
 	votingController := &controller.VotingController{
-		Service: votingService,
-		Logger:  logger,
+		Service:      votingService,
+		ActionsURL:   cfg.ActionsURL,
+		ActionSecret: []byte(cfg.ActionSecret),
+		ServerURL:    cfg.MattermostURL,
+		Logger:       logger,
 	}
 
+	// The bot sets the client token, which overdue votings need as soon as
+	// they are scheduled.
 	mattermostBot, err := mattermost.NewMattermostBot(cfg, votingController, logger)
 	if err != nil {
 		logger.Error("Ошибка создания Mattermost бота", slog.Any("error", err))
 		return
 	}
 
-	mattermostBot.Start()
+	votingService.Deadlines = service.NewDeadlineScheduler(votingController.AdvanceVoting, logger)
+	votingService.Posts = service.NewPostRefresher(votingController.RefreshVotingPost, service.PostRefreshDelay, logger)
+	if err := votingService.SchedulePending(); err != nil {
+		logger.Error("Ошибка загрузки расписания голосований", slog.Any("error", err))
+		return
+	}
+
+	if cfg.RegisterCommand {
+		if err := mattermostBot.RegisterCommand(cfg.ActionsURL); err != nil {
+			logger.Error("Ошибка регистрации команды /poll", slog.Any("error", err))
+		}
+	}
+
 	mattermost.SetupGracefulShutdown(mattermostBot)
+	mattermostBot.Start()
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 )
 
@@ -13,8 +14,31 @@ type Config struct {
 	TarantoolPassword         string `json:"tarantool_password"`
 	AppPort                   string `json:"app_port"`
 	Mattermost_url_web_socket string `json:"Mattermost_url_web_socket"`
+	Storage                   string `json:"storage"`
+	SQLDSN                    string `json:"sql_dsn"`
+	// BallotSecret keys the voter hashes of anonymous votings; they can't be
+	// created without it, and changing it breaks the ones already running.
+	BallotSecret string `json:"-"`
+	// ActionsURL is the address Mattermost reaches the bot's HTTP server at,
+	// for vote buttons; they are not posted without it.
+	ActionsURL string `json:"actions_url"`
+	// ActionSecret signs the vote buttons and the voting dialog; changing it
+	// breaks the buttons of the votings already posted.
+	ActionSecret string `json:"-"`
+	// CommandToken is the token of the /poll slash command, which the
+	// command endpoint checks. RegisterCommand makes the bot create the
+	// command itself at ActionsURL and take its token instead.
+	CommandToken    string `json:"-"`
+	RegisterCommand bool   `json:"register_command"`
 }
 
+const (
+	StorageTarantool = "tarantool"
+	StorageMemory    = "memory"
+	StorageSQLite    = "sqlite"
+	StoragePostgres  = "postgres"
+)
+
 func LoadConfig() (*Config, error) {
 	config := Config{
 		MattermostToken:           os.Getenv("BOT_TOKEN"),
@@ -25,12 +49,45 @@ func LoadConfig() (*Config, error) {
 		TarantoolPassword:         os.Getenv("TARANTOOL_PASSWORD"),
 		AppPort:                   os.Getenv("APP_PORT"),
 		Mattermost_url_web_socket: os.Getenv("MATTERMOST_URL_WEB_SOCKET"),
+		Storage:                   os.Getenv("STORAGE"),
+		SQLDSN:                    os.Getenv("SQL_DSN"),
+		BallotSecret:              os.Getenv("BALLOT_SECRET"),
+		ActionsURL:                os.Getenv("ACTIONS_URL"),
+		ActionSecret:              os.Getenv("ACTION_SECRET"),
+		CommandToken:              os.Getenv("COMMAND_TOKEN"),
+		RegisterCommand:           os.Getenv("REGISTER_COMMAND") == "true",
+	}
+
+	if config.Storage == "" {
+		config.Storage = StorageTarantool
 	}
 
 	// Проверка, что все необходимые переменные установлены (опционально)
-	if config.MattermostToken == "" || config.MattermostURL == "" || config.TarantoolHost == "" || config.TarantoolPort == "" || config.TarantoolUser == "" || config.TarantoolPassword == "" || config.AppPort == "" || config.Mattermost_url_web_socket == "" {
+	if config.MattermostToken == "" || config.MattermostURL == "" || config.AppPort == "" || config.Mattermost_url_web_socket == "" {
 		return nil, os.ErrNotExist // Или другая подходящая ошибка
 	}
 
+	if config.ActionsURL != "" && config.ActionSecret == "" {
+		return nil, fmt.Errorf("ACTIONS_URL needs ACTION_SECRET")
+	}
+
+	if config.RegisterCommand && config.ActionsURL == "" {
+		return nil, fmt.Errorf("REGISTER_COMMAND needs ACTIONS_URL")
+	}
+
+	switch config.Storage {
+	case StorageTarantool:
+		if config.TarantoolHost == "" || config.TarantoolPort == "" || config.TarantoolUser == "" || config.TarantoolPassword == "" {
+			return nil, os.ErrNotExist
+		}
+	case StorageSQLite, StoragePostgres:
+		if config.SQLDSN == "" {
+			return nil, os.ErrNotExist
+		}
+	case StorageMemory:
+	default:
+		return nil, fmt.Errorf("unknown storage %q", config.Storage)
+	}
+
 	return &config, nil
 }
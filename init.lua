@@ -4,30 +4,14 @@ box.cfg{
   memtx_memory = 128 * 1024 * 1024 -- 128MB
 }
 
--- polls (Voting model)
-box.schema.space.create('votings', { if_not_exists = true })
-box.space.votings:format({
-  { name = 'id',         type = 'string' },
-  { name = 'creator_id',  type = 'string' },
-  { name = 'question',   type = 'string' },
-  { name = 'channel_id', type = 'string' },
-  { name = 'options',    type = 'array', array_type = 'string' }, -- Changed to array of strings
-  { name = 'created_at', type = 'number' }, -- Store as timestamp (seconds since epoch)
-  { name = 'closed_at',  type = 'number' }, -- Store as timestamp (seconds since epoch)
-  { name = 'results',    type = 'array', array_type = 'unsigned' }, -- Array of unsigned integers (results)
-  { name = 'is_active',  type = 'boolean' },
-})
-
-box.space.votings:create_index('primary', {
-  parts = {'id'},
-  unique = true,
-  if_not_exists = true
-})
-
-if not box.space.votings.index.channel_id then
-  box.space.votings:create_index('channel_id', {
-      parts = {'channel_id'},
-      unique = false,
-      if_not_exists = true
-  })
-end
\ No newline at end of file
+-- Spaces, indexes and functions are created by the bot on startup,
+-- see pkg/repository/TarantoolMigrations.go.
+local user = os.getenv('TARANTOOL_USER')
+if user ~= nil and user ~= '' and user ~= 'guest' then
+  box.schema.user.create(user, { password = os.getenv('TARANTOOL_PASSWORD'), if_not_exists = true })
+  -- The bot owns the spaces and functions its migrations create, which lets
+  -- it alter and drop them; it needs no rights over anything else.
+  box.schema.user.grant(user, 'read,write,execute', 'universe', nil, { if_not_exists = true })
+  box.schema.user.grant(user, 'create,alter', 'space', nil, { if_not_exists = true })
+  box.schema.user.grant(user, 'create', 'function', nil, { if_not_exists = true })
+end
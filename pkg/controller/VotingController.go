@@ -4,16 +4,39 @@ import (
 	"fmt"
 	"go-voting-bot/pkg/dto"
 	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
 	"go-voting-bot/pkg/service"
+	"go-voting-bot/pkg/utils"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
 )
 
 type VotingController struct {
 	Service *service.VotingService
-	Logger  *slog.Logger
+	// ActionsURL is where Mattermost reaches the bot's HTTP server, such as
+	// "http://app:8080"; without it votings are posted without buttons.
+	ActionsURL string
+	// ActionSecret signs the context of the vote buttons and the state of
+	// the voting dialog.
+	ActionSecret []byte
+	// ServerURL is the Mattermost address users open, for links to posts.
+	ServerURL string
+	Logger    *slog.Logger
+}
+
+// WithReplies returns a copy of the controller whose service holds back
+// the messages of a slash command in the replies.
+func (con *VotingController) WithReplies(replies *service.CommandReplies) *VotingController {
+	scoped := *con
+	scoped.Service = con.Service.WithReplies(replies)
+	return &scoped
 }
 
 func (con *VotingController) CreateVoting(c *gin.Context, CommandRequest dto.CommandRequest) {
@@ -27,18 +50,394 @@ func (con *VotingController) CreateVoting(c *gin.Context, CommandRequest dto.Com
 	if err != nil {
 		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при создании голосования.")
 		errors.ErrorHandler(c, err)
+		return
 	}
-	message := fmt.Sprintf("Голосование создано!\n**%s**\n", voting.Question)
-	for i, option := range voting.Options {
-		message += fmt.Sprintf(":white_check_mark: %s - `/vote %s %d`\n", option, voting.ID, i+1)
+	switch voting.State {
+	case model.StateDraft:
+		con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf(
+			"Черновик голосования `%s` создан. Чтобы опубликовать его, используйте `/poll open %s`", voting.ID, voting.ID))
+	case model.StateScheduled:
+		con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf(
+			"Голосование с ID `%s` откроется %s.", voting.ID, voting.OpensAt.Format("02.01.2006 15:04")))
+	default:
+		con.announceVoting(voting, "Голосование создано!")
+		con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Голосование с ID `%s` создано.", voting.ID))
 	}
-	message += fmt.Sprintf("\nЧтобы просмотреть результаты, используйте `/results %s`", voting.ID)
-	message += fmt.Sprintf("\nЧтобы завершить голосование, используйте `/end %s`", voting.ID)
 
-	con.Service.PostMessage(channelID, message)
+	c.Status(http.StatusOK)
+}
+
+// NewVotingDialog opens the poll creation dialog. Mattermost opens a dialog
+// only with the trigger ID of a click or a slash command, which posted
+// commands lack, so for them the user gets a button that opens it.
+func (con *VotingController) NewVotingDialog(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	if con.ActionsURL == "" {
+		con.Service.PostEphemeralMessage(channelID, userID,
+			"Диалог создания голосования не настроен: администратору бота нужно задать ACTIONS_URL. Используйте `/poll create Вопрос | Вариант 1 | Вариант 2`.")
+		err := errors.BadRequest.New(errors.UnavailableResource.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "ACTIONS_URL", "dialogs need ACTIONS_URL"))
+		return
+	}
+	if CommandRequest.TriggerID != "" {
+		if err := con.openVotingDialog(CommandRequest.TriggerID, channelID, userID); err != nil {
+			con.Service.PostEphemeralMessage(channelID, userID, "Не удалось открыть диалог, попробуйте ещё раз.")
+			errors.ErrorHandler(c, err)
+			return
+		}
+		c.Status(http.StatusOK)
+		return
+	}
+	attachment := &mattermodel.SlackAttachment{
+		Actions: []*mattermodel.PostAction{{
+			Id:          "newpoll",
+			Type:        mattermodel.PostActionTypeButton,
+			Name:        "Создать голосование",
+			Integration: &mattermodel.PostActionIntegration{URL: con.actionURL("/actions/new-poll")},
+		}},
+	}
+	con.Service.PostEphemeralAttachments(channelID, userID, "Вопрос, варианты и настройки голосования заполняются в диалоге.",
+		[]*mattermodel.SlackAttachment{attachment})
+
+	c.Status(http.StatusOK)
+}
+
+// OpenVotingDialog handles a click on the button posted by NewVotingDialog.
+func (con *VotingController) OpenVotingDialog(c *gin.Context) {
+	var action mattermodel.PostActionIntegrationRequest
+	if err := c.ShouldBindJSON(&action); err != nil {
+		err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "body", "not an integration action request"))
+		return
+	}
+	if action.TriggerId == "" || action.UserId == "" {
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "body", "integration action request without trigger or user"))
+		return
+	}
+	if err := con.openVotingDialog(action.TriggerId, action.ChannelId, action.UserId); err != nil {
+		c.JSON(http.StatusOK, mattermodel.PostActionIntegrationResponse{EphemeralText: "Не удалось открыть диалог, попробуйте ещё раз."})
+		return
+	}
+	c.JSON(http.StatusOK, mattermodel.PostActionIntegrationResponse{})
+}
+
+// openVotingDialog opens the poll creation dialog; its state is signed for
+// the user and the channel, which the submission has to match.
+func (con *VotingController) openVotingDialog(triggerID, channelID, userID string) error {
+	return con.Service.OpenDialog(mattermodel.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       con.actionURL("/dialogs/new-poll"),
+		Dialog:    votingDialog(utils.DialogToken(con.ActionSecret, channelID, userID)),
+	})
+}
+
+func votingDialog(state string) mattermodel.Dialog {
+	return mattermodel.Dialog{
+		CallbackId:  "new-poll",
+		Title:       "Новое голосование",
+		SubmitLabel: "Создать",
+		State:       state,
+		Elements: []mattermodel.DialogElement{
+			{
+				DisplayName: "Вопрос",
+				Name:        dto.DialogQuestion,
+				Type:        "text",
+				MaxLength:   300,
+			},
+			{
+				DisplayName: "Варианты",
+				Name:        dto.DialogOptions,
+				Type:        "textarea",
+				HelpText:    "Каждый вариант с новой строки, не меньше двух.",
+				MaxLength:   3000,
+			},
+			{
+				DisplayName: "Тип",
+				Name:        dto.DialogKind,
+				Type:        "select",
+				Default:     dto.DialogKindSingle,
+				Options: []*mattermodel.PostActionOptions{
+					{Text: "Один вариант", Value: dto.DialogKindSingle},
+					{Text: "Несколько вариантов", Value: dto.DialogKindMultiple},
+					{Text: "Рейтинговое, мгновенный второй тур", Value: dto.DialogKindRanked},
+					{Text: "Рейтинговое, метод Шульце", Value: dto.DialogKindSchulze},
+					{Text: "Оценки от 1 до 5", Value: dto.DialogKindScore},
+				},
+			},
+			{
+				DisplayName: "Срок",
+				Name:        dto.DialogDeadline,
+				Type:        "text",
+				Placeholder: "2h или 2026-11-01 18:00",
+				HelpText:    "Длительность, например 30m, 2h или 1d12h, или время окончания. Без срока голосование завершает автор.",
+				Optional:    true,
+			},
+			{
+				DisplayName: "Результаты видны",
+				Name:        dto.DialogResults,
+				Type:        "select",
+				Default:     "always",
+				Options: []*mattermodel.PostActionOptions{
+					{Text: "Всем и всегда", Value: "always"},
+					{Text: "Проголосовавшим", Value: "voted"},
+					{Text: "Только автору", Value: "creator"},
+					{Text: "Всем после завершения", Value: "closed"},
+				},
+			},
+			{
+				DisplayName: "Анонимное",
+				Name:        dto.DialogAnonymous,
+				Type:        "bool",
+				Placeholder: "Не хранить, кто как проголосовал",
+				Optional:    true,
+			},
+		},
+	}
+}
 
+// SubmitVotingDialog handles a submitted poll creation dialog. Mistakes are
+// shown next to their fields in the dialog rather than posted to the chat.
+func (con *VotingController) SubmitVotingDialog(c *gin.Context) {
+	var submission mattermodel.SubmitDialogRequest
+	if err := c.ShouldBindJSON(&submission); err != nil {
+		err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "body", "not a dialog submission"))
+		return
+	}
+	channelID := submission.ChannelId
+	userID := submission.UserId
+	if userID == "" || !utils.ValidDialogToken(con.ActionSecret, channelID, userID, submission.State) {
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "state", "dialog state is missing or not signed by the bot"))
+		return
+	}
+	if submission.Cancelled {
+		c.Status(http.StatusOK)
+		return
+	}
+	con.Logger.Info("Handling poll dialog", slog.String("channel_id", channelID), slog.String("user_id", userID))
+
+	voting, fieldErrors, err := con.Service.CreateDialogVoting(dialogRequest(submission.Submission), channelID, userID)
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusOK, mattermodel.SubmitDialogResponse{Errors: fieldErrors})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, mattermodel.SubmitDialogResponse{Error: "Произошла ошибка при создании голосования."})
+		return
+	}
+	con.announceVoting(voting, "Голосование создано!")
 	con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Голосование с ID `%s` создано.", voting.ID))
 
+	c.JSON(http.StatusOK, mattermodel.SubmitDialogResponse{})
+}
+
+// dialogRequest reads the submitted fields; Mattermost leaves out empty
+// optional ones and may send a checkbox as a string.
+func dialogRequest(submission map[string]interface{}) dto.VotingDialogRequest {
+	text := func(name string) string {
+		value, _ := submission[name].(string)
+		return value
+	}
+	request := dto.VotingDialogRequest{
+		Question: text(dto.DialogQuestion),
+		Options:  text(dto.DialogOptions),
+		Kind:     text(dto.DialogKind),
+		Deadline: text(dto.DialogDeadline),
+		Results:  text(dto.DialogResults),
+	}
+	switch anonymous := submission[dto.DialogAnonymous].(type) {
+	case bool:
+		request.Anonymous = anonymous
+	case string:
+		request.Anonymous = anonymous == "true"
+	}
+	return request
+}
+
+// announceVoting posts the card of an open voting and remembers the post,
+// so that the card can follow the tallies and take reaction votes.
+func (con *VotingController) announceVoting(voting model.Voting, title string) {
+	postID := con.Service.PostAttachments(voting.ChannelID, votingCard(voting, title, nil), con.voteButtons(voting))
+	if postID == "" {
+		return
+	}
+	con.Service.SetVotingPost(voting.ID, postID)
+	if voting.Settings.Reactions {
+		voting.PostID = postID
+		con.Service.AddReactions(voting)
+	}
+}
+
+// RefreshVotingPost brings the card of a voting up to date with its tallies
+// and state. The service's PostRefresher calls it after votes and on close.
+func (con *VotingController) RefreshVotingPost(votingID string) {
+	voting, err := con.Service.GetVoting(votingID)
+	if err != nil {
+		con.Logger.Warn("Failed to refresh voting post", slog.String("voting_id", votingID), slog.Any("error", err))
+		return
+	}
+	if voting.PostID == "" {
+		return
+	}
+	results, err := con.Service.VotingResults(voting)
+	if err != nil {
+		con.Logger.Error("Failed to count voting results", slog.String("voting_id", votingID), slog.Any("error", err))
+		return
+	}
+
+	var buttons []*mattermodel.SlackAttachment
+	if voting.IsOpen() {
+		buttons = con.voteButtons(voting)
+	}
+	title := "Голосование " + utils.FormatState(string(voting.State))
+	con.Service.PatchPost(voting.PostID, votingCard(voting, title, &results), buttons)
+}
+
+// votingCard is the message of a voting post: the options, with bars and
+// counts when results are given and everyone may see them, and while the
+// voting is open the commands to take part in it.
+func votingCard(voting model.Voting, title string, results *dto.VotingResultsResponse) string {
+	message := fmt.Sprintf("%s\n**%s**\n", title, voting.Question)
+	tallies := results != nil && len(results.Results) == len(voting.Options) && voting.ResultsPublic()
+	for i, option := range voting.Options {
+		line := fmt.Sprintf("%d. %s", i+1, option)
+		// Ranked and score votings take every option in one command, shown
+		// below, and reaction votings take no commands.
+		if voting.IsOpen() && voting.Settings.Kind == model.KindChoice && !voting.Settings.Reactions {
+			line = fmt.Sprintf(":white_check_mark: %s - `/poll vote %s %d`", option, voting.ID, i+1)
+		}
+		if tallies {
+			result := results.Results[i]
+			percentage := result.Percentage
+			if results.Weights != "" {
+				percentage = result.WeightedPercentage
+			}
+			line += fmt.Sprintf("  `%s` %d (%.0f%%)", tallyBar(percentage), result.VoteCount, percentage)
+		}
+		message += line + "\n"
+	}
+	if results != nil {
+		message += fmt.Sprintf("\nПроголосовали: %d %s\n", results.TotalVoters,
+			utils.Plural(results.TotalVoters, "участник", "участника", "участников"))
+	}
+	if !voting.IsOpen() {
+		return message + fmt.Sprintf("\nЧтобы просмотреть результаты, используйте `/poll results %s`", voting.ID)
+	}
+
+	if settings := voting.Settings; settings.IsScore() {
+		message += fmt.Sprintf("\nОцените каждый вариант от %d до %d, перечислив оценки по порядку через запятую: `/poll vote %s %s`",
+			settings.MinScore, settings.MaxScore, voting.ID, utils.ScoreExample(settings.MinScore, settings.MaxScore, len(voting.Options)))
+	} else if voting.Settings.IsRanked() {
+		message += fmt.Sprintf("\nРасставьте варианты по предпочтению, начиная с лучшего: `/poll vote %s 3>1>2`", voting.ID)
+		if voting.Settings.Tally == model.TallySchulze {
+			message += "\nПобедитель определяется методом Шульце по попарным сравнениям вариантов."
+		} else {
+			message += "\nПобедитель определяется по системе мгновенного второго тура: варианты с наименьшим числом голосов выбывают по очереди."
+		}
+	} else if voting.Settings.Reactions {
+		message += fmt.Sprintf("\nГолосуйте реакциями :one: – :%s: под этим сообщением; снятая реакция отзывает голос.",
+			utils.NumberEmojis[len(voting.Options)-1])
+		if limit := voting.Settings.ChoiceLimit(); limit > 1 {
+			message += fmt.Sprintf(" Можно выбрать до %d %s.", limit, utils.Plural(limit, "варианта", "вариантов", "вариантов"))
+		}
+	} else if limit := voting.Settings.ChoiceLimit(); limit > 1 {
+		message += fmt.Sprintf("\nМожно выбрать до %d %s через запятую: `/poll vote %s 1,2`",
+			limit, utils.Plural(limit, "варианта", "вариантов", "вариантов"), voting.ID)
+	}
+	if voting.Settings.Public {
+		message += fmt.Sprintf("\nГолосование публичное: кто за что проголосовал, покажет `/poll voters %s`", voting.ID)
+	}
+	if voting.Settings.Anonymous {
+		message += "\nГолосование анонимное: бот не хранит, кто как проголосовал."
+	}
+	if quorum := voting.Settings.Quorum; quorum > 0 {
+		message += fmt.Sprintf("\nКворум: должны проголосовать не меньше %s участников канала.", formatShare(quorum))
+	}
+	if threshold := voting.Settings.Threshold; threshold > 0 {
+		message += fmt.Sprintf("\nРешение принимается, если лидирующий вариант наберёт не меньше %s голосов.", formatShare(threshold))
+	}
+	if voting.Settings.Weights != "" {
+		message += fmt.Sprintf("\nГолоса взвешиваются по таблице «%s»: `/poll weights show %s`", voting.Settings.Weights, voting.Settings.Weights)
+	}
+	if visibility := voting.Settings.Results; visibility != model.ResultsAlways {
+		message += "\n" + resultsVisibilityHints[visibility]
+	}
+	message += fmt.Sprintf("\nЧтобы просмотреть результаты, используйте `/poll results %s`", voting.ID)
+	message += fmt.Sprintf("\nЧтобы завершить голосование, используйте `/poll close %s`", voting.ID)
+	if !voting.Deadline.IsZero() {
+		message += fmt.Sprintf("\nГолосование завершится автоматически %s", voting.Deadline.Format("02.01.2006 15:04"))
+	}
+
+	return message
+}
+
+// tallyBar draws a share in percent as a bar of ten cells.
+func tallyBar(percentage float64) string {
+	filled := min(max(int(math.Round(percentage/10)), 0), 10)
+	return strings.Repeat("▓", filled) + strings.Repeat("░", 10-filled)
+}
+
+func (con *VotingController) OpenVoting(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	request := dto.VotingRequest{
+		Text: CommandRequest.Message,
+	}
+	con.Logger.Info("Handling /open command", slog.String("channel_id", channelID), slog.String("user_id", userID))
+
+	voting, err := con.Service.OpenVoting(request, channelID, userID)
+	if err != nil {
+		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при открытии голосования.")
+		errors.ErrorHandler(c, err)
+		return
+	}
+
+	con.announceVoting(voting, "Голосование открыто!")
+	c.Status(http.StatusOK)
+}
+
+func (con *VotingController) ReopenVoting(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	request := dto.VotingRequest{
+		Text: CommandRequest.Message,
+	}
+	con.Logger.Info("Handling /reopen command", slog.String("channel_id", channelID), slog.String("user_id", userID))
+
+	voting, err := con.Service.ReopenVoting(request, channelID, userID)
+	if err != nil {
+		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при открытии голосования.")
+		errors.ErrorHandler(c, err)
+		return
+	}
+
+	con.announceVoting(voting, "Голосование снова открыто!")
+	c.Status(http.StatusOK)
+}
+
+func (con *VotingController) ArchiveVoting(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	request := dto.VotingRequest{
+		Text: CommandRequest.Message,
+	}
+	con.Logger.Info("Handling /archive command", slog.String("channel_id", channelID), slog.String("user_id", userID))
+
+	voting, err := con.Service.ArchiveVoting(request, channelID, userID)
+	if err != nil {
+		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при архивации голосования.")
+		errors.ErrorHandler(c, err)
+		return
+	}
+
+	con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Голосование **%s** отправлено в архив.", voting.Question))
 	c.Status(http.StatusOK)
 }
 
@@ -51,17 +450,138 @@ func (con *VotingController) AddVote(c *gin.Context, CommandRequest dto.CommandR
 	}
 	con.Logger.Info("Handling /vote command", slog.String("channel_id", channelID), slog.String("user_id", userID))
 
-	_, err := con.Service.AddNewVote(request, channelID, userID)
+	vote, err := con.Service.AddNewVote(request, channelID, userID)
 	if err != nil {
 		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при обработке голоса.")
 		errors.ErrorHandler(c, err)
+		return
 	}
 
-	message := fmt.Sprintf("Новый голос учтён!")
+	con.Service.PostEphemeralMessage(channelID, userID, voteMessage(vote))
 
-	con.Service.PostMessage(channelID, message)
+	c.Status(http.StatusOK)
+}
 
-	con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Ваш голос учтён!"))
+// voteMessage tells the voter what became of their vote.
+func voteMessage(vote dto.VoteResponse) string {
+	publicNote := ""
+	if vote.Public {
+		publicNote = fmt.Sprintf("\nГолосование публичное: другие участники увидят ваш выбор в `/poll voters %s`.", vote.VotingID)
+	}
+	switch vote.PreviousOption {
+	case "":
+		return fmt.Sprintf("Ваш голос за «%s» учтён!", vote.Option) + publicNote
+	case vote.Option:
+		return fmt.Sprintf("Вы уже голосовали за «%s».", vote.Option)
+	}
+	if vote.Option == "" {
+		return fmt.Sprintf("Ваш голос за «%s» отозван.", vote.PreviousOption)
+	}
+	return fmt.Sprintf("Ваш голос изменён: «%s» → «%s».", vote.PreviousOption, vote.Option) + publicNote
+}
+
+// VoteAction handles a click on a vote button of a voting post, sent by
+// Mattermost as an integration action request.
+func (con *VotingController) VoteAction(c *gin.Context) {
+	var action mattermodel.PostActionIntegrationRequest
+	if err := c.ShouldBindJSON(&action); err != nil {
+		err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "body", "not an integration action request"))
+		return
+	}
+	votingID, _ := action.Context["voting_id"].(string)
+	option, isNumber := action.Context["option"].(float64)
+	token, _ := action.Context["token"].(string)
+	if votingID == "" || !isNumber || option != float64(int(option)) || action.UserId == "" ||
+		!utils.ValidActionToken(con.ActionSecret, votingID, int(option), token) {
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "context", "vote button context is missing or not signed by the bot"))
+		return
+	}
+	// The voter is not logged with the voting, which may be anonymous.
+	con.Logger.Info("Handling vote button", slog.String("channel_id", action.ChannelId), slog.String("voting_id", votingID))
+
+	// Mattermost shows its own error on top of the answer unless it is a
+	// 200, so a refused vote is explained in the answer alone.
+	replies := &service.CommandReplies{ChannelID: action.ChannelId, UserID: action.UserId}
+	vote, err := con.Service.WithReplies(replies).VoteOption(votingID, int(option), action.ChannelId, action.UserId)
+	if err != nil {
+		text := replies.EphemeralText()
+		if text == "" {
+			text = "Произошла ошибка при обработке голоса."
+		}
+		c.JSON(http.StatusOK, mattermodel.PostActionIntegrationResponse{EphemeralText: text})
+		return
+	}
+	c.JSON(http.StatusOK, mattermodel.PostActionIntegrationResponse{EphemeralText: voteMessage(vote)})
+}
+
+// VoteReaction handles a numbered reaction added to or removed from a voting
+// post, sent by Mattermost over the WebSocket.
+func (con *VotingController) VoteReaction(reaction *mattermodel.Reaction, channelID string, added bool) {
+	vote, err := con.Service.VoteReaction(reaction, channelID, added)
+	if err != nil {
+		con.Logger.Warn("Failed to vote by reaction", slog.String("post_id", reaction.PostId),
+			slog.String("user_id", reaction.UserId), slog.Any("error", err))
+		return
+	}
+	if vote.VotingID != "" {
+		con.Service.PostEphemeralMessage(channelID, reaction.UserId, voteMessage(vote))
+	}
+}
+
+// voteButtons are the attachments with one vote button per option, for the
+// votings that can be voted in with a click.
+func (con *VotingController) voteButtons(voting model.Voting) []*mattermodel.SlackAttachment {
+	if con.ActionsURL == "" || voting.Settings.Kind != model.KindChoice || voting.Settings.Reactions {
+		return nil
+	}
+	text := "Нажмите на вариант, чтобы проголосовать."
+	if limit := voting.Settings.ChoiceLimit(); limit > 1 {
+		text = fmt.Sprintf("Нажимайте на варианты, чтобы выбрать до %d %s; повторное нажатие снимает выбор.",
+			limit, utils.Plural(limit, "варианта", "вариантов", "вариантов"))
+	}
+	attachment := &mattermodel.SlackAttachment{Text: text}
+	for i, option := range voting.Options {
+		attachment.Actions = append(attachment.Actions, &mattermodel.PostAction{
+			Id:   fmt.Sprintf("option%d", i+1),
+			Type: mattermodel.PostActionTypeButton,
+			Name: fmt.Sprintf("%d. %s", i+1, option),
+			Integration: &mattermodel.PostActionIntegration{
+				URL: con.actionURL("/actions/vote"),
+				Context: map[string]interface{}{
+					"voting_id": voting.ID,
+					"option":    i,
+					"token":     utils.ActionToken(con.ActionSecret, voting.ID, i),
+				},
+			},
+		})
+	}
+	return []*mattermodel.SlackAttachment{attachment}
+}
+
+// actionURL is the address Mattermost sends the requests of the path to.
+func (con *VotingController) actionURL(path string) string {
+	return strings.TrimSuffix(con.ActionsURL, "/") + path
+}
+
+func (con *VotingController) RemoveVote(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	request := dto.VotingRequest{
+		Text: CommandRequest.Message,
+	}
+	con.Logger.Info("Handling /unvote command", slog.String("channel_id", channelID), slog.String("user_id", userID))
+
+	vote, err := con.Service.RemoveVote(request, channelID, userID)
+	if err != nil {
+		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при отзыве голоса.")
+		errors.ErrorHandler(c, err)
+		return
+	}
+
+	con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Ваш голос за «%s» отозван.", vote.PreviousOption))
 
 	c.Status(http.StatusOK)
 }
@@ -77,22 +597,340 @@ func (con *VotingController) GetResults(c *gin.Context, CommandRequest dto.Comma
 
 	votingResults, VotingID, err := con.Service.GetResultsByVotingId(request, channelID, userID)
 	if err != nil {
-		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при обработке голоса.")
+		con.postResultsError(channelID, userID, err, "Произошла ошибка при обработке голоса.")
+		errors.ErrorHandler(c, err)
+		return
+	}
+
+	message := formatResults(votingResults)
+
+	con.Logger.Info("Results requested", slog.String("voting_id", VotingID), slog.String("user_id", userID))
+
+	if votingResults.Private {
+		con.Service.PostEphemeralMessage(channelID, userID, message)
+	} else {
+		con.Service.PostMessage(channelID, message)
+	}
+
+	c.Status(http.StatusOK)
+}
+
+var resultsVisibilityHints = map[model.ResultsVisibility]string{
+	model.ResultsVoted:   "Результаты видны только проголосовавшим, а после завершения — всем.",
+	model.ResultsCreator: "Результаты видит только автор голосования.",
+	model.ResultsClosed:  "Результаты скрыты до завершения голосования.",
+}
+
+var hiddenResultsMessages = map[model.ResultsVisibility]string{
+	model.ResultsVoted:   "Результаты этого голосования видны только проголосовавшим. Проголосуйте, чтобы их увидеть.",
+	model.ResultsCreator: "Результаты этого голосования видит только его автор.",
+	model.ResultsClosed:  "Результаты этого голосования скрыты до его завершения.",
+}
+
+// postResultsError explains to the user why results are hidden from them,
+// or posts the fallback message for any other error.
+func (con *VotingController) postResultsError(channelID, userID string, err error, fallback string) {
+	if errors.GetType(err) != errors.Forbidden {
+		con.Service.PostEphemeralMessage(channelID, userID, fallback)
+		return
+	}
+	field := errors.GetErrorContext(err)["field"]
+	if field == service.ForeignChannelField {
+		con.Service.PostEphemeralMessage(channelID, userID, "Это голосование создано в другом канале, смотрите его там.")
+		return
+	}
+	visibility := model.ResultsVisibility(field)
+	con.Service.PostEphemeralMessage(channelID, userID, hiddenResultsMessages[visibility])
+}
+
+func (con *VotingController) GetVoters(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	request := dto.VotingRequest{
+		Text: CommandRequest.Message,
+	}
+	con.Logger.Info("Handling /voters command", slog.String("channel_id", channelID), slog.String("user_id", userID))
+
+	voters, err := con.Service.VotingVoters(request, channelID, userID)
+	if err != nil {
+		con.postResultsError(channelID, userID, err, "Произошла ошибка при получении списка проголосовавших.")
 		errors.ErrorHandler(c, err)
 		return
 	}
 
+	if voters.Private {
+		con.Service.PostEphemeralMessage(channelID, userID, formatVoters(voters))
+	} else {
+		con.Service.PostMessage(channelID, formatVoters(voters))
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// formatVoters lists the voters of a public voting option by option, with
+// the place or score they gave it in ranked and score votings.
+func formatVoters(voters dto.VotingVotersResponse) string {
+	message := fmt.Sprintf("**Кто как проголосовал: %s**\n", voters.Question)
+	for _, option := range voters.Options {
+		names := make([]string, len(option.Voters))
+		for i, voter := range option.Voters {
+			names[i] = voter.Name
+			switch {
+			case voter.Rank > 0:
+				names[i] += fmt.Sprintf(" (%d-е место)", voter.Rank)
+			case voter.Score != nil:
+				names[i] += fmt.Sprintf(" (%d)", *voter.Score)
+			}
+		}
+		if len(names) == 0 {
+			names = []string{"—"}
+		}
+		message += fmt.Sprintf("%s: %s\n", option.Option, strings.Join(names, ", "))
+	}
+	return strings.TrimSuffix(message, "\n")
+}
+
+// AdvanceVoting is called by the scheduler when a voting is due to open or
+// close, and announces the change in the voting's channel.
+func (con *VotingController) AdvanceVoting(votingID string) {
+	voting, err := con.Service.AdvanceVoting(votingID)
+	if err != nil {
+		con.Logger.Warn("Failed to advance scheduled voting", slog.String("voting_id", votingID), slog.Any("error", err))
+		return
+	}
+
+	if voting.IsOpen() {
+		con.announceVoting(voting, "Голосование открыто!")
+		return
+	}
+	results, err := con.Service.VotingResults(voting)
+	if err != nil {
+		con.Logger.Error("Failed to count voting results", slog.String("voting_id", votingID), slog.Any("error", err))
+		return
+	}
+	message := fmt.Sprintf("Время голосования истекло, голосование **%s** завершено.", voting.Question)
+	if !voting.ResultsPublic() {
+		con.Service.PostMessage(voting.ChannelID, message+"\nРезультаты видит только автор голосования.")
+		con.Service.PostEphemeralMessage(voting.ChannelID, voting.CreatorID, formatResults(results))
+		return
+	}
+	con.Service.PostMessage(voting.ChannelID, message+"\n\n"+formatResults(results))
+}
+
+func formatResults(votingResults dto.VotingResultsResponse) string {
 	message := fmt.Sprintf("**Результаты голосования: %s**\n", votingResults.Question)
+	message += formatTally(votingResults)
+	if votingResults.Verdict != nil {
+		message += "\n\n" + formatVerdict(*votingResults.Verdict)
+	}
+	return message
+}
 
+// formatTally shows the counts of a voting the way its kind is tallied.
+func formatTally(votingResults dto.VotingResultsResponse) string {
+	if votingResults.Runoff != nil {
+		return formatRunoff(votingResults)
+	}
+	if votingResults.Schulze != nil {
+		return formatSchulze(votingResults)
+	}
+	if votingResults.Scores != nil {
+		return formatScores(votingResults)
+	}
+
+	message := ""
 	for i, result := range votingResults.Results {
-		message += fmt.Sprintf("%d. %s: %d (%.2f%%)\n", i+1, result.Option, result.VoteCount, result.Percentage)
+		message += fmt.Sprintf("%d. %s: %d (%.2f%%)", i+1, result.Option, result.VoteCount, result.Percentage)
+		if votingResults.Weights != "" {
+			message += fmt.Sprintf(" · с весом %g (%.2f%%)", result.WeightedVotes, result.WeightedPercentage)
+		}
+		message += "\n"
+	}
+	message += fmt.Sprintf("\nПроголосовали: %d %s", votingResults.TotalVoters,
+		utils.Plural(votingResults.TotalVoters, "участник", "участника", "участников"))
+	if votingResults.MaxChoices > 1 {
+		message += fmt.Sprintf(", выбрано вариантов: %d", votingResults.TotalVotes)
 	}
+	if votingResults.Weights != "" {
+		message += fmt.Sprintf("\nСуммарный вес голосов: %g (таблица «%s»)", votingResults.TotalWeight, votingResults.Weights)
+	}
+	return message
+}
 
-	con.Logger.Info("Results requested", slog.String("voting_id", VotingID), slog.String("user_id", userID))
+var outcomeNames = map[dto.Outcome]string{
+	dto.OutcomePassed:   "принято",
+	dto.OutcomeFailed:   "не принято",
+	dto.OutcomeNoQuorum: "кворум не набран",
+	dto.OutcomeUnknown:  "не удалось проверить кворум",
+}
 
-	con.Service.PostMessage(channelID, message)
+// formatVerdict states the outcome of a voting with a quorum or a threshold
+// together with the numbers it was checked against.
+func formatVerdict(verdict dto.VerdictResponse) string {
+	title := "Итог"
+	if !verdict.Final {
+		title = "Предварительный итог"
+	}
+	message := fmt.Sprintf("**%s: %s", title, outcomeNames[verdict.Outcome])
+	if verdict.Outcome == dto.OutcomePassed && verdict.Leader != "" {
+		message += " — " + verdict.Leader
+	}
+	message += "**"
 
-	c.Status(http.StatusOK)
+	if verdict.Quorum > 0 {
+		switch verdict.Outcome {
+		case dto.OutcomeUnknown:
+			message += fmt.Sprintf("\nКворум %s: не удалось получить число участников канала, проголосовали %d.",
+				formatShare(verdict.Quorum), verdict.Voters)
+		default:
+			reached := "набран"
+			if verdict.Outcome == dto.OutcomeNoQuorum {
+				reached = "не набран"
+			}
+			message += fmt.Sprintf("\nКворум %s %s: проголосовали %d из %d участников канала, нужно не меньше %d.",
+				formatShare(verdict.Quorum), reached, verdict.Voters, verdict.Members, verdict.RequiredVoters)
+		}
+	}
+	if verdict.Threshold > 0 {
+		votes := "голосов"
+		if verdict.Weighted {
+			votes = "веса голосов"
+		}
+		if verdict.Leader == "" {
+			message += fmt.Sprintf("\nПорог принятия %s %s: ни один вариант не лидирует единолично.", formatShare(verdict.Threshold), votes)
+		} else {
+			message += fmt.Sprintf("\nПорог принятия %s %s: у варианта «%s» %s.",
+				formatShare(verdict.Threshold), votes, verdict.Leader, formatShare(verdict.LeaderShare))
+		}
+	}
+	return message
+}
+
+// formatShare shows a share between 0 and 1 as a percentage, as in "60%" or
+// "66.67%".
+func formatShare(share float64) string {
+	return strconv.FormatFloat(math.Round(share*10000)/100, 'f', -1, 64) + "%"
+}
+
+// formatRunoff shows the instant-runoff rounds of a ranked voting: the votes
+// of each round and where the ballots of eliminated options went.
+func formatRunoff(votingResults dto.VotingResultsResponse) string {
+	runoff := votingResults.Runoff
+	message := ""
+	for i, round := range runoff.Rounds {
+		counts := make([]string, len(round.Votes))
+		for j, count := range round.Votes {
+			counts[j] = fmt.Sprintf("%s — %d", count.Option, count.Votes)
+		}
+		message += fmt.Sprintf("Раунд %d: %s\n", i+1, strings.Join(counts, " · "))
+		if len(round.Eliminated) == 0 {
+			continue
+		}
+
+		transfers := make([]string, 0, len(round.Transfers)+1)
+		for _, transfer := range round.Transfers {
+			transfers = append(transfers, fmt.Sprintf("%s +%d", transfer.Option, transfer.Votes))
+		}
+		if round.Exhausted > 0 {
+			transfers = append(transfers, fmt.Sprintf("выбыло бюллетеней: %d", round.Exhausted))
+		}
+		message += fmt.Sprintf("Выбывает %s", strings.Join(round.Eliminated, ", "))
+		if len(transfers) > 0 {
+			message += " → " + strings.Join(transfers, ", ")
+		}
+		message += "\n"
+	}
+
+	switch len(runoff.Winners) {
+	case 0:
+		message += "\nГолосов пока нет."
+	case 1:
+		message += fmt.Sprintf("\n**Победитель: %s**", runoff.Winners[0])
+	default:
+		message += fmt.Sprintf("\n**Ничья: %s**", strings.Join(runoff.Winners, ", "))
+	}
+	message += fmt.Sprintf("\nПроголосовали: %d %s", votingResults.TotalVoters,
+		utils.Plural(votingResults.TotalVoters, "участник", "участника", "участников"))
+	return message
+}
+
+// formatScores shows the statistics and the distribution of the scores each
+// option of a score voting received.
+func formatScores(votingResults dto.VotingResultsResponse) string {
+	message := ""
+	for i, option := range votingResults.Scores.Options {
+		message += fmt.Sprintf("%d. **%s**: среднее %.2f · медиана %g · отклонение %.2f\n",
+			i+1, option.Option, option.Mean, option.Median, option.StdDev)
+		histogram := make([]string, len(option.Histogram))
+		for j, count := range option.Histogram {
+			histogram[j] = fmt.Sprintf("%d — %d", count.Score, count.Count)
+		}
+		message += fmt.Sprintf("    оценки: %s\n", strings.Join(histogram, " · "))
+	}
+	message += fmt.Sprintf("\nПроголосовали: %d %s", votingResults.TotalVoters,
+		utils.Plural(votingResults.TotalVoters, "участник", "участника", "участников"))
+	return message
+}
+
+// formatSchulze shows the first preferences and the Schulze matrices of a
+// ranked voting. Matrix rows and columns are option numbers.
+func formatSchulze(votingResults dto.VotingResultsResponse) string {
+	schulze := votingResults.Schulze
+	message := "Первые места:\n"
+	for i, result := range votingResults.Results {
+		message += fmt.Sprintf("%d. %s: %d\n", i+1, result.Option, result.VoteCount)
+	}
+	message += "\nПопарные предпочтения (сколько голосов за вариант в строке против варианта в столбце):\n"
+	message += formatMatrix(schulze.Pairwise)
+	message += "Сильнейшие пути:\n"
+	message += formatMatrix(schulze.Strongest)
+
+	if schulze.CondorcetWinner != "" {
+		message += fmt.Sprintf("\nПобедитель по Кондорсе: %s", schulze.CondorcetWinner)
+	} else {
+		message += "\nПобедителя по Кондорсе нет: ни один вариант не выигрывает у всех остальных."
+	}
+	switch len(schulze.Winners) {
+	case 0:
+		message += "\nГолосов пока нет."
+	case 1:
+		message += fmt.Sprintf("\n**Победитель по Шульце: %s**", schulze.Winners[0])
+	default:
+		message += fmt.Sprintf("\n**Ничья по Шульце: %s**", strings.Join(schulze.Winners, ", "))
+	}
+	message += fmt.Sprintf("\nПроголосовали: %d %s", votingResults.TotalVoters,
+		utils.Plural(votingResults.TotalVoters, "участник", "участника", "участников"))
+	return message
+}
+
+// formatMatrix renders a square matrix as a code block with option numbers
+// as headers; the diagonal is left blank.
+func formatMatrix(matrix [][]int) string {
+	width := len(fmt.Sprint(len(matrix)))
+	for _, row := range matrix {
+		for _, value := range row {
+			width = max(width, len(fmt.Sprint(value)))
+		}
+	}
+
+	message := "```\n" + strings.Repeat(" ", width)
+	for j := range matrix {
+		message += fmt.Sprintf(" %*d", width, j+1)
+	}
+	message += "\n"
+	for i, row := range matrix {
+		message += fmt.Sprintf("%*d", width, i+1)
+		for j, value := range row {
+			if i == j {
+				message += fmt.Sprintf(" %*s", width, "—")
+				continue
+			}
+			message += fmt.Sprintf(" %*d", width, value)
+		}
+		message += "\n"
+	}
+	return message + "```\n"
 }
 
 func (con *VotingController) EndVoting(c *gin.Context, CommandRequest dto.CommandRequest) {
@@ -104,14 +942,26 @@ func (con *VotingController) EndVoting(c *gin.Context, CommandRequest dto.Comman
 	}
 	con.Logger.Info("Handling /end command", slog.String("channel_id", channelID), slog.String("user_id", userID))
 
-	votingID, err := con.Service.EndVotingByVotingId(request, channelID, userID)
+	voting, err := con.Service.EndVotingByVotingId(request, channelID, userID)
 	if err != nil {
 		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при обработке голоса.")
 		errors.ErrorHandler(c, err)
 		return
 	}
 
-	message := fmt.Sprintf("Голосование **%s** завершено.", votingID)
+	message := fmt.Sprintf("Голосование **%s** завершено.", voting.ID)
+	if voting.Settings.HasVerdict() {
+		results, err := con.Service.VotingResults(voting)
+		switch {
+		case err != nil:
+			con.Logger.Error("Failed to count voting results", slog.String("voting_id", voting.ID), slog.Any("error", err))
+		case results.Verdict == nil:
+		case voting.ResultsPublic():
+			message += "\n\n" + formatVerdict(*results.Verdict)
+		default:
+			con.Service.PostEphemeralMessage(channelID, userID, formatVerdict(*results.Verdict))
+		}
+	}
 	con.Service.PostMessage(channelID, message)
 
 	c.Status(http.StatusOK)
@@ -138,3 +988,151 @@ func (con *VotingController) DeleteVoting(c *gin.Context, CommandRequest dto.Com
 	con.Service.PostEphemeralMessage(channelID, userID, message)
 	c.Status(http.StatusOK)
 }
+
+// searchItemLink is a permalink to the post of a found voting, or its short
+// ID when it has no post to link to.
+func (con *VotingController) searchItemLink(item dto.VotingSearchItem) string {
+	if item.PostID == "" || item.TeamName == "" || con.ServerURL == "" {
+		return fmt.Sprintf("`%s`", item.ShortID)
+	}
+	return fmt.Sprintf("[`%s`](%s/%s/pl/%s)", item.ShortID, strings.TrimSuffix(con.ServerURL, "/"), item.TeamName, item.PostID)
+}
+
+// Weights handles "/poll weights set|show ...".
+func (con *VotingController) Weights(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	action, rest, _ := strings.Cut(CommandRequest.Message, " ")
+	request := dto.VotingRequest{
+		Text: strings.TrimSpace(rest),
+	}
+	con.Logger.Info("Handling /weights command", slog.String("channel_id", channelID), slog.String("user_id", userID),
+		slog.String("action", action))
+
+	switch strings.ToLower(action) {
+	case "set":
+		entry, err := con.Service.SetWeight(request, channelID, userID)
+		if err != nil {
+			con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при изменении таблицы весов.")
+			errors.ErrorHandler(c, err)
+			return
+		}
+		con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf("В таблице «%s» для %s задан вес %g.",
+			entry.Table, strings.Fields(request.Text)[1], entry.Weight))
+	case "show":
+		table, err := con.Service.ShowWeights(request, channelID, userID)
+		if err != nil {
+			con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при получении таблицы весов.")
+			errors.ErrorHandler(c, err)
+			return
+		}
+		con.Service.PostEphemeralMessage(channelID, userID, formatWeights(table))
+	default:
+		con.Service.PostEphemeralMessage(channelID, userID, "Используйте: /poll weights set <таблица> <@пользователь|role:роль|group:группа> <вес> или /poll weights show [таблица]")
+		errors.ErrorHandler(c, errors.BadRequest.New(errors.InvalidFormat.Message()))
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+var weightSubjectNames = map[string]string{
+	"user":  "пользователь",
+	"role":  "роль",
+	"group": "группа",
+}
+
+func formatWeights(table dto.WeightTableResponse) string {
+	if table.Table == "" {
+		if len(table.Tables) == 0 {
+			return "В этом канале нет таблиц весов. Создайте таблицу: `/poll weights set <таблица> <@пользователь|role:роль|group:группа> <вес>`"
+		}
+		return fmt.Sprintf("**Таблицы весов канала:** %s\nПодробнее: `/poll weights show <таблица>`", strings.Join(table.Tables, ", "))
+	}
+
+	message := fmt.Sprintf("**Таблица весов «%s»**\n", table.Table)
+	for _, entry := range table.Entries {
+		name := entry.Name
+		if entry.Subject == "user" {
+			name = "@" + name
+		}
+		message += fmt.Sprintf("%s %s — %g\n", weightSubjectNames[entry.Subject], name, entry.Weight)
+	}
+	message += fmt.Sprintf("\nОстальные участники голосуют с весом %g.", model.DefaultWeight)
+	return message
+}
+
+func (con *VotingController) ListVotings(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	request := dto.VotingRequest{
+		Text: CommandRequest.Message,
+	}
+	con.Logger.Info("Handling /list command", slog.String("channel_id", channelID), slog.String("user_id", userID))
+
+	list, err := con.Service.ListVotings(request, channelID, userID)
+	if err != nil {
+		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при получении списка голосований.")
+		errors.ErrorHandler(c, err)
+		return
+	}
+
+	if len(list.Votings) == 0 {
+		con.Service.PostEphemeralMessage(channelID, userID, "Голосований не найдено.")
+		c.Status(http.StatusOK)
+		return
+	}
+
+	message := fmt.Sprintf("**Голосования в канале** (страница %d)\n", list.Page)
+	for _, item := range list.Votings {
+		status := utils.FormatState(item.State)
+		message += fmt.Sprintf("`%s` **%s** — @%s · %s · %d %s · %s\n",
+			item.ShortID, item.Question, item.Creator, status,
+			item.VoteCount, utils.Plural(item.VoteCount, "голос", "голоса", "голосов"),
+			utils.FormatAge(time.Since(item.CreatedAt)))
+	}
+	if list.HasMore {
+		message += fmt.Sprintf("\nСледующая страница: `/poll list %s %d`", list.Status, list.Page+1)
+	}
+
+	con.Service.PostEphemeralMessage(channelID, userID, message)
+	c.Status(http.StatusOK)
+}
+
+func (con *VotingController) SearchVotings(c *gin.Context, CommandRequest dto.CommandRequest) {
+	channelID := CommandRequest.ChannelID
+	userID := CommandRequest.UserID
+
+	request := dto.VotingRequest{
+		Text: CommandRequest.Message,
+	}
+	con.Logger.Info("Handling /search command", slog.String("channel_id", channelID), slog.String("user_id", userID))
+
+	found, err := con.Service.SearchVotings(request, channelID, userID)
+	if err != nil {
+		con.Service.PostEphemeralMessage(channelID, userID, "Произошла ошибка при поиске голосований.")
+		errors.ErrorHandler(c, err)
+		return
+	}
+
+	if len(found.Votings) == 0 {
+		con.Service.PostEphemeralMessage(channelID, userID, fmt.Sprintf("По запросу «%s» ничего не найдено.", found.Query))
+		c.Status(http.StatusOK)
+		return
+	}
+
+	message := fmt.Sprintf("**Результаты поиска «%s»**\n", found.Query)
+	for i, item := range found.Votings {
+		status := utils.FormatState(item.State)
+		channel := "канал недоступен"
+		if item.ChannelName != "" {
+			channel = "~" + item.ChannelName
+		}
+		message += fmt.Sprintf("%d. %s **%s** — %s · %s · %s\n",
+			i+1, con.searchItemLink(item), item.Question, channel, status, item.CreatedAt.Format("02.01.2006"))
+	}
+
+	con.Service.PostEphemeralMessage(channelID, userID, message)
+	c.Status(http.StatusOK)
+}
@@ -1,7 +1,10 @@
-package dto
-
-type CommandRequest struct {
-	Message   string
-	UserID    string 
-	ChannelID string
-}
\ No newline at end of file
+package dto
+
+type CommandRequest struct {
+	Message   string
+	UserID    string
+	ChannelID string
+	// TriggerID is only set for slash commands; it lets the bot open a
+	// dialog for the user.
+	TriggerID string
+}
@@ -0,0 +1,23 @@
+package dto
+
+// ScoreResultsResponse summarises a score voting option by option.
+type ScoreResultsResponse struct {
+	MinScore int            `json:"min_score"`
+	MaxScore int            `json:"max_score"`
+	Options  []OptionScores `json:"options"`
+}
+
+type OptionScores struct {
+	Option string  `json:"option"`
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"std_dev"`
+	// Histogram counts the voters who gave each score, from MinScore up.
+	Histogram []ScoreCount `json:"histogram"`
+}
+
+type ScoreCount struct {
+	Score int `json:"score"`
+	Count int `json:"count"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+// Outcome is the verdict on a voting with a quorum or a threshold.
+type Outcome string
+
+const (
+	OutcomePassed   Outcome = "passed"
+	OutcomeFailed   Outcome = "failed"
+	OutcomeNoQuorum Outcome = "no_quorum"
+	// OutcomeUnknown is given when the channel members could not be counted
+	// to check the quorum.
+	OutcomeUnknown Outcome = "unknown"
+)
+
+// VerdictResponse holds the outcome of a voting and the numbers it was
+// checked against. Shares are between 0 and 1.
+type VerdictResponse struct {
+	Outcome Outcome `json:"outcome"`
+	// Final is false while the voting still takes votes.
+	Final bool `json:"final"`
+
+	Voters int `json:"voters"`
+	// Members is the number of channel members when the quorum was checked,
+	// and RequiredVoters the voters the quorum asks of them.
+	Members        int     `json:"members,omitempty"`
+	Quorum         float64 `json:"quorum,omitempty"`
+	RequiredVoters int     `json:"required_voters,omitempty"`
+
+	Threshold float64 `json:"threshold,omitempty"`
+	// Leader is the option with the most votes, empty on a tie for first
+	// place or without votes. LeaderShare is its share of the voters, or of
+	// the total weight in a weighted voting.
+	Leader      string  `json:"leader,omitempty"`
+	LeaderShare float64 `json:"leader_share,omitempty"`
+	Weighted    bool    `json:"weighted,omitempty"`
+}
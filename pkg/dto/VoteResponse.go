@@ -0,0 +1,12 @@
+package dto
+
+// VoteResponse describes a cast or retracted vote. Options are labels such as
+// "1. Да"; several chosen options are comma-separated.
+type VoteResponse struct {
+	VotingID       string `json:"voting_id"`
+	Question       string `json:"question"`
+	PreviousOption string `json:"previous_option,omitempty"`
+	Option         string `json:"option,omitempty"`
+	// Public is set when other users can see the vote with /poll voters.
+	Public bool `json:"public,omitempty"`
+}
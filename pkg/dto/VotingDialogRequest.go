@@ -0,0 +1,32 @@
+package dto
+
+// Names of the fields of the poll creation dialog, as submitted by
+// Mattermost and as keys of the per-field errors sent back to it.
+const (
+	DialogQuestion  = "question"
+	DialogOptions   = "options"
+	DialogKind      = "kind"
+	DialogDeadline  = "deadline"
+	DialogResults   = "results"
+	DialogAnonymous = "anonymous"
+)
+
+// Values of the kind field of the poll creation dialog.
+const (
+	DialogKindSingle   = "single"
+	DialogKindMultiple = "multiple"
+	DialogKindRanked   = "ranked"
+	DialogKindSchulze  = "schulze"
+	DialogKindScore    = "score"
+)
+
+// VotingDialogRequest is a submitted poll creation dialog. Options holds one
+// option per line; Deadline is a duration such as "2h" or a closing time.
+type VotingDialogRequest struct {
+	Question  string
+	Options   string
+	Kind      string
+	Deadline  string
+	Results   string
+	Anonymous bool
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type VotingListResponse struct {
+	Status  string           `json:"status"`
+	Page    int              `json:"page"`
+	HasMore bool             `json:"has_more"`
+	Votings []VotingListItem `json:"votings"`
+}
+
+type VotingListItem struct {
+	ID        string    `json:"id"`
+	ShortID   string    `json:"short_id"`
+	Question  string    `json:"question"`
+	Creator   string    `json:"creator"`
+	State     string    `json:"state"`
+	VoteCount int       `json:"vote_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
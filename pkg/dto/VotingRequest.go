@@ -1,5 +1,14 @@
 package dto
 
+// VotingRequest is the text of a /poll command after its action. Mattermost
+// posts the whole slash command as this form, with the action still in Text.
 type VotingRequest struct {
-	Text string `form:"text" binding:"required"`
+	Text string `form:"text"`
+
+	Token     string `form:"token"`
+	TeamID    string `form:"team_id"`
+	ChannelID string `form:"channel_id"`
+	UserID    string `form:"user_id"`
+	Command   string `form:"command"`
+	TriggerID string `form:"trigger_id"`
 }
@@ -1,14 +1,68 @@
 package dto
 
 type VotingResultsResponse struct {
-	Question   string   `json:"question"`
-	Options    []string `json:"options"`
-	Results    []Result `json:"results"`
-	TotalVotes int      `json:"total_votes"`
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	Results  []Result `json:"results"`
+	// TotalVotes sums the selections; with multiple choice one voter counts
+	// once per selected option.
+	TotalVotes  int `json:"total_votes"`
+	TotalVoters int `json:"total_voters"`
+	MaxChoices  int `json:"max_choices"`
+	// Runoff or Schulze is set for ranked votings, by their tally method;
+	// Results then hold first preferences.
+	Runoff  *RunoffResults  `json:"runoff,omitempty"`
+	Schulze *SchulzeResults `json:"schulze,omitempty"`
+	// Weights names the weight table of a weighted voting; TotalWeight sums
+	// the weights of its voters.
+	Weights     string  `json:"weights,omitempty"`
+	TotalWeight float64 `json:"total_weight,omitempty"`
+	// Scores is set for score votings instead of Results.
+	Scores *ScoreResultsResponse `json:"scores,omitempty"`
+	// Verdict is set for votings with a quorum or a threshold.
+	Verdict *VerdictResponse `json:"verdict,omitempty"`
+	// Private results may only be shown to the user who asked for them.
+	Private bool `json:"private,omitempty"`
 }
 
 type Result struct {
-	Option     string  `json:"option"`
-	VoteCount  int     `json:"vote_count"`
+	Option    string `json:"option"`
+	VoteCount int    `json:"vote_count"`
+	// Percentage is the share of voters who chose the option.
 	Percentage float64 `json:"percentage"`
+	// WeightedVotes sums the weights of the ballots for the option, and
+	// WeightedPercentage is its share of the total weight; both are set in
+	// weighted votings only.
+	WeightedVotes      float64 `json:"weighted_votes,omitempty"`
+	WeightedPercentage float64 `json:"weighted_percentage,omitempty"`
+}
+
+// RunoffResults are the instant-runoff rounds of a ranked voting.
+type RunoffResults struct {
+	Rounds []RunoffRound `json:"rounds"`
+	// Winners holds one option, several on a tie, or none without ballots.
+	Winners []string `json:"winners"`
+}
+
+type RunoffRound struct {
+	Votes      []RunoffCount `json:"votes"`
+	Eliminated []string      `json:"eliminated,omitempty"`
+	Transfers  []RunoffCount `json:"transfers,omitempty"`
+	Exhausted  int           `json:"exhausted,omitempty"`
+}
+
+// RunoffCount is a number of ballots counted for, or moved to, an option.
+type RunoffCount struct {
+	Option string `json:"option"`
+	Votes  int    `json:"votes"`
+}
+
+// SchulzeResults are the matrices of a Schulze tally, indexed like Options,
+// row over column.
+type SchulzeResults struct {
+	Pairwise  [][]int `json:"pairwise"`
+	Strongest [][]int `json:"strongest"`
+	// CondorcetWinner is empty when no option beats every other.
+	CondorcetWinner string   `json:"condorcet_winner,omitempty"`
+	Winners         []string `json:"winners"`
 }
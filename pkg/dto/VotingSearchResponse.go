@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+type VotingSearchResponse struct {
+	Query   string             `json:"query"`
+	Votings []VotingSearchItem `json:"votings"`
+}
+
+type VotingSearchItem struct {
+	ID          string    `json:"id"`
+	ShortID     string    `json:"short_id"`
+	Question    string    `json:"question"`
+	ChannelID   string    `json:"channel_id"`
+	ChannelName string    `json:"channel_name"`
+	State       string    `json:"state"`
+	CreatedAt   time.Time `json:"created_at"`
+	// TeamName is the team of the channel, for links to the voting post;
+	// empty for channels outside of teams.
+	TeamName string `json:"team_name,omitempty"`
+	// PostID is the voting card post, empty until the voting is announced.
+	PostID string `json:"post_id,omitempty"`
+}
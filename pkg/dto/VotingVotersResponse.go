@@ -0,0 +1,25 @@
+package dto
+
+// VotingVotersResponse lists who voted for each option of a public voting.
+type VotingVotersResponse struct {
+	VotingID string         `json:"voting_id"`
+	Question string         `json:"question"`
+	Options  []OptionVoters `json:"options"`
+	// Private lists may only be shown to the user who asked for them.
+	Private bool `json:"private,omitempty"`
+}
+
+type OptionVoters struct {
+	Option string  `json:"option"`
+	Voters []Voter `json:"voters"`
+}
+
+// Voter is a user who picked, ranked or scored an option.
+type Voter struct {
+	// Name is the Mattermost display name.
+	Name string `json:"name"`
+	// Rank is the place the voter gave the option in a ranked voting,
+	// from 1; Score is their score for it in a score voting.
+	Rank  int  `json:"rank,omitempty"`
+	Score *int `json:"score,omitempty"`
+}
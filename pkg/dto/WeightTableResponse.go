@@ -0,0 +1,16 @@
+package dto
+
+// WeightTableResponse lists the entries of one weight table, or the names of
+// the channel's tables when no table was asked for.
+type WeightTableResponse struct {
+	Table   string        `json:"table,omitempty"`
+	Tables  []string      `json:"tables,omitempty"`
+	Entries []WeightEntry `json:"entries,omitempty"`
+}
+
+type WeightEntry struct {
+	// Subject is "user", "role" or "group"; Name is a username for users.
+	Subject string  `json:"subject"`
+	Name    string  `json:"name"`
+	Weight  float64 `json:"weight"`
+}
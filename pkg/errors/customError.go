@@ -14,6 +14,8 @@ const (
 	WrongType
 	InvalidFormat
 	UnavailableResource
+	InvalidTransition
+	Forbidden
 )
 
 type ErrorType uint
@@ -91,6 +93,10 @@ func (t ErrorType) Message() string {
 		return "Wrong data type delivered."
 	case InvalidFormat:
 		return "Message has wrong format."
+	case InvalidTransition:
+		return "Conflict: The voting state does not allow this action."
+	case Forbidden:
+		return "Forbidden: The resource is not available to this user."
 	default:
 		return "Unknown error occurred."
 	}
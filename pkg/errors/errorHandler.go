@@ -22,6 +22,10 @@ func ErrorHandler(c *gin.Context, err error) {
 		status = http.StatusBadRequest
 	case InvalidFormat:
 		status = http.StatusBadRequest
+	case InvalidTransition:
+		status = http.StatusConflict
+	case Forbidden:
+		status = http.StatusForbidden
 	default:
 		status = http.StatusInternalServerError
 
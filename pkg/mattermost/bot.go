@@ -1,12 +1,16 @@
 package mattermost
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"go-voting-bot/config"
 	"go-voting-bot/pkg/controller"
 	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/service"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
@@ -16,6 +20,9 @@ import (
 	"github.com/mattermost/mattermost-server/v6/model"
 )
 
+// pollCommandPath is where Mattermost sends the /poll slash command.
+const pollCommandPath = "/commands/poll"
+
 type MattermostBot struct {
 	BotID      string
 	TeamID     string
@@ -25,6 +32,8 @@ type MattermostBot struct {
 	Token      string
 	AppPort    string
 	Ws_URL     string
+	// CommandToken is the token the /poll slash command requests carry.
+	CommandToken string
 }
 
 func NewMattermostBot(cfg *config.Config, con *controller.VotingController, logger *slog.Logger) (*MattermostBot, error) {
@@ -44,6 +53,8 @@ func NewMattermostBot(cfg *config.Config, con *controller.VotingController, logg
 		return nil, fmt.Errorf("bot is not a member of any team")
 	}
 
+	con.Service.BotID = user.Id
+
 	return &MattermostBot{
 		BotID:      user.Id,
 		TeamID:     teams[0].Id,
@@ -53,6 +64,8 @@ func NewMattermostBot(cfg *config.Config, con *controller.VotingController, logg
 		Token:      cfg.MattermostToken,
 		AppPort:    cfg.AppPort,
 		Ws_URL:     cfg.Mattermost_url_web_socket,
+
+		CommandToken: cfg.CommandToken,
 	}, nil
 }
 
@@ -62,6 +75,10 @@ func (b *MattermostBot) Start() {
 	go b.listenToEvents()
 
 	router := gin.Default()
+	router.POST("/actions/vote", b.Controller.VoteAction)
+	router.POST("/actions/new-poll", b.Controller.OpenVotingDialog)
+	router.POST("/dialogs/new-poll", b.Controller.SubmitVotingDialog)
+	router.POST(pollCommandPath, b.handleSlashCommand)
 	port := b.AppPort
 	address := ":" + port
 	b.Logger.Info("Starting HTTP server with Gin", slog.String("port", port))
@@ -75,8 +92,6 @@ func (b *MattermostBot) listenToEvents() {
 
 	for {
 		b.Logger.Info("Connecting to Mattermost WebSocket")
-		fmt.Println(b.Token)
-		fmt.Println(b.Ws_URL)
 		webSocketClient, err := model.NewWebSocketClient4(b.Ws_URL, b.Token)
 		if err != nil {
 			b.Logger.Warn("Failed to connect to WebSocket, retrying...", slog.Any("error", err))
@@ -95,6 +110,11 @@ func (b *MattermostBot) listenToEvents() {
 }
 
 func (b *MattermostBot) handleWebSocketEvent(event *model.WebSocketEvent) {
+	switch event.EventType() {
+	case model.WebsocketEventReactionAdded, model.WebsocketEventReactionRemoved:
+		b.handleReaction(event)
+		return
+	}
 	if event.EventType() != model.WebsocketEventPosted {
 		return
 	}
@@ -109,10 +129,30 @@ func (b *MattermostBot) handleWebSocketEvent(event *model.WebSocketEvent) {
 	if post.UserId == b.BotID {
 		return
 	}
-	c, _ := gin.CreateTestContext(nil)
-	b.processCommand(c, post)
+	b.processCommand(discardContext(), post)
+}
+
+// handleReaction passes reactions to the controller, which counts those on
+// voting posts as votes.
+func (b *MattermostBot) handleReaction(event *model.WebSocketEvent) {
+	data, _ := event.GetData()["reaction"].(string)
+	reaction := &model.Reaction{}
+	if err := json.Unmarshal([]byte(data), reaction); err != nil {
+		b.Logger.Warn("Could not unmarshal reaction from WebSocket event", slog.Any("error", err))
+		return
+	}
+	if reaction.UserId == b.BotID {
+		return
+	}
+	added := event.EventType() == model.WebsocketEventReactionAdded
+	b.Controller.VoteReaction(reaction, event.GetBroadcast().ChannelId, added)
 }
 
+const (
+	commandUsage   = "Ипспользуйте:/poll create, new, vote, unvote, results, close, open, reopen, archive, delete, list, search, weights, voters"
+	unknownCommand = "Недопустимая команда. Ипспользуйте: create, new, vote, unvote, results, close, open, reopen, archive, delete, list, search, weights, voters"
+)
+
 func (b *MattermostBot) processCommand(c *gin.Context, post *model.Post) {
 	if !strings.HasPrefix(post.Message, "/poll") {
 		return
@@ -120,7 +160,7 @@ func (b *MattermostBot) processCommand(c *gin.Context, post *model.Post) {
 
 	args := strings.Fields(post.Message)
 	if len(args) < 2 {
-		b.Controller.Service.PostMessage(post.ChannelId, "Ипспользуйте:/poll create, vote, results, close, delete")
+		b.Controller.Service.PostMessage(post.ChannelId, commandUsage)
 		return
 	}
 
@@ -135,20 +175,132 @@ func (b *MattermostBot) processCommand(c *gin.Context, post *model.Post) {
 		ChannelID: post.ChannelId,
 	}
 
+	if !runCommand(b.Controller, c, action, dto) {
+		b.Controller.Service.PostMessage(post.ChannelId, unknownCommand)
+	}
+}
+
+// runCommand hands a /poll command to the controller. It returns false for
+// an unknown action.
+func runCommand(con *controller.VotingController, c *gin.Context, action string, dto dto.CommandRequest) bool {
 	switch action {
 	case "create":
-		b.Controller.CreateVoting(c, dto)
+		con.CreateVoting(c, dto)
+	case "new":
+		con.NewVotingDialog(c, dto)
 	case "vote":
-		b.Controller.AddVote(c, dto)
+		con.AddVote(c, dto)
+	case "unvote":
+		con.RemoveVote(c, dto)
 	case "results":
-		b.Controller.GetResults(c, dto)
+		con.GetResults(c, dto)
 	case "close":
-		b.Controller.EndVoting(c, dto)
+		con.EndVoting(c, dto)
 	case "delete":
-		b.Controller.DeleteVoting(c, dto)
+		con.DeleteVoting(c, dto)
+	case "list":
+		con.ListVotings(c, dto)
+	case "search":
+		con.SearchVotings(c, dto)
+	case "open":
+		con.OpenVoting(c, dto)
+	case "reopen":
+		con.ReopenVoting(c, dto)
+	case "archive":
+		con.ArchiveVoting(c, dto)
+	case "weights":
+		con.Weights(c, dto)
+	case "voters":
+		con.GetVoters(c, dto)
 	default:
-		b.Controller.Service.PostMessage(post.ChannelId, "Недопустимая команда. Ипспользуйте: create, vote, results, close, delete")
+		return false
+	}
+	return true
+}
+
+// handleSlashCommand serves the /poll slash command. The messages to the
+// command's channel come back in the command response, in the channel or
+// only to the user; voting cards are still posted by the bot, which keeps
+// their post IDs to update them.
+func (b *MattermostBot) handleSlashCommand(c *gin.Context) {
+	var command dto.VotingRequest
+	if err := c.ShouldBind(&command); err != nil {
+		err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "body", "not a slash command request"))
+		return
+	}
+	if b.CommandToken == "" || subtle.ConstantTimeCompare([]byte(command.Token), []byte(b.CommandToken)) != 1 {
+		b.Logger.Warn("Slash command with a wrong token", slog.String("user_id", command.UserID))
+		err := errors.Forbidden.New(errors.Forbidden.Message())
+		errors.ErrorHandler(c, errors.AddErrorContext(err, "token", "slash command token does not match"))
+		return
+	}
+
+	action, messageBody, _ := strings.Cut(strings.TrimSpace(command.Text), " ")
+	if action == "" {
+		c.JSON(http.StatusOK, &model.CommandResponse{ResponseType: model.CommandResponseTypeEphemeral, Text: commandUsage})
+		return
+	}
+	request := dto.CommandRequest{
+		Message:   strings.TrimSpace(messageBody),
+		UserID:    command.UserID,
+		ChannelID: command.ChannelID,
+		TriggerID: command.TriggerID,
+	}
+
+	// The controller answers the command through the replies, so its own
+	// status and error bodies go nowhere.
+	replies := &service.CommandReplies{ChannelID: command.ChannelID, UserID: command.UserID}
+	if !runCommand(b.Controller.WithReplies(replies), discardContext(), strings.ToLower(action), request) {
+		c.JSON(http.StatusOK, &model.CommandResponse{ResponseType: model.CommandResponseTypeEphemeral, Text: unknownCommand})
+		return
+	}
+	c.JSON(http.StatusOK, replies.Response())
+}
+
+// RegisterCommand creates the /poll slash command of the bot's team at
+// actionsURL, or points an existing one there, and takes its token. The bot
+// needs the permission to manage slash commands.
+func (b *MattermostBot) RegisterCommand(actionsURL string) error {
+	client := b.Controller.Service.Client
+	url := strings.TrimSuffix(actionsURL, "/") + pollCommandPath
+
+	commands, _, err := client.ListCommands(b.TeamID, true)
+	if err != nil {
+		return fmt.Errorf("failed to list slash commands: %w", err)
+	}
+	for _, command := range commands {
+		if command.Trigger != "poll" {
+			continue
+		}
+		if command.URL != url || command.Method != model.CommandMethodPost {
+			command.URL, command.Method = url, model.CommandMethodPost
+			if command, _, err = client.UpdateCommand(command); err != nil {
+				return fmt.Errorf("failed to update /poll command: %w", err)
+			}
+		}
+		b.CommandToken = command.Token
+		b.Logger.Info("Using existing /poll command", slog.String("command_id", command.Id))
+		return nil
+	}
+
+	command, _, err := client.CreateCommand(&model.Command{
+		TeamId:           b.TeamID,
+		Trigger:          "poll",
+		Method:           model.CommandMethodPost,
+		URL:              url,
+		DisplayName:      "Голосования",
+		Description:      "Создание голосований и голосование в канале",
+		AutoComplete:     true,
+		AutoCompleteDesc: "create, new, vote, unvote, results, close, open, reopen, archive, delete, list, search, weights, voters",
+		AutoCompleteHint: "[команда]",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create /poll command: %w", err)
 	}
+	b.CommandToken = command.Token
+	b.Logger.Info("Registered /poll command", slog.String("command_id", command.Id))
+	return nil
 }
 func SetupGracefulShutdown(bot *MattermostBot) {
 	c := make(chan os.Signal, 1)
@@ -156,6 +308,8 @@ func SetupGracefulShutdown(bot *MattermostBot) {
 	go func() {
 		<-c
 		bot.Logger.Info("Shutting down...")
+		bot.Controller.Service.Deadlines.Stop()
+		bot.Controller.Service.Posts.Stop()
 		os.Exit(0)
 	}()
 }
@@ -0,0 +1,53 @@
+package mattermost
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// discardContext is a gin context for running a controller outside of an
+// HTTP request of its own, such as for a command read from the WebSocket or
+// a slash command answered with the collected replies: whatever the
+// controller responds with goes nowhere.
+func discardContext() *gin.Context {
+	return &gin.Context{Writer: &discardResponse{header: http.Header{}, status: http.StatusOK}}
+}
+
+// discardResponse is a gin.ResponseWriter that keeps only the status.
+type discardResponse struct {
+	header http.Header
+	status int
+	size   int
+}
+
+func (w *discardResponse) Header() http.Header { return w.header }
+
+func (w *discardResponse) WriteHeader(status int) { w.status = status }
+
+func (w *discardResponse) WriteHeaderNow() {}
+
+func (w *discardResponse) Write(data []byte) (int, error) {
+	w.size += len(data)
+	return len(data), nil
+}
+
+func (w *discardResponse) WriteString(s string) (int, error) { return w.Write([]byte(s)) }
+
+func (w *discardResponse) Status() int { return w.status }
+
+func (w *discardResponse) Size() int { return w.size }
+
+func (w *discardResponse) Written() bool { return w.size > 0 }
+
+func (w *discardResponse) Flush() {}
+
+func (w *discardResponse) CloseNotify() <-chan bool { return nil }
+
+func (w *discardResponse) Pusher() http.Pusher { return nil }
+
+func (w *discardResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
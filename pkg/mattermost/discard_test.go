@@ -0,0 +1,18 @@
+package mattermost
+
+import (
+	"go-voting-bot/pkg/errors"
+	"net/http"
+	"testing"
+)
+
+// Controllers respond to the discard context as to a request, and nothing
+// of it is sent anywhere.
+func TestDiscardContext(t *testing.T) {
+	c := discardContext()
+	c.Status(http.StatusOK)
+	errors.ErrorHandler(c, errors.NotFound.New(errors.NotFound.Message()))
+	if status := c.Writer.Status(); status != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", status, http.StatusNotFound)
+	}
+}
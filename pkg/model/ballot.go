@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type Ballot struct {
+	VotingID string `json:"voting_id"`
+	// UserID is the voter's Mattermost user ID, or in an anonymous voting a
+	// keyed hash of it that can't be turned back into the ID.
+	UserID string `json:"user_id"`
+	// Choices are zero-based option indexes, at least one. In a ranked
+	// voting they are in order of preference, the first is the favourite.
+	Choices []int `json:"choices"`
+	// Scores are the scores given in a score voting, one per option in
+	// Choices; nil for other votings.
+	Scores []int `json:"scores,omitempty"`
+	// Weight is what the ballot counted with, see VotingSettings.Weights.
+	Weight float64 `json:"weight"`
+	// CastAt is when the ballot was cast; zero in an anonymous voting, where
+	// the time could tell who voted.
+	CastAt time.Time `json:"cast_at"`
+}
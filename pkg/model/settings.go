@@ -0,0 +1,114 @@
+package model
+
+import "math"
+
+// VotingKind is how voters answer a voting.
+type VotingKind string
+
+const (
+	// KindChoice votings take one or more picked options; it is the default
+	// and is stored as the empty kind.
+	KindChoice VotingKind = ""
+	// KindRanked votings take options in order of preference and are
+	// counted by instant-runoff.
+	KindRanked VotingKind = "ranked"
+	// KindScore votings take a score within a range for every option.
+	KindScore VotingKind = "score"
+)
+
+// TallyMethod is how the rankings of a ranked voting are counted.
+type TallyMethod string
+
+const (
+	// TallyIRV is instant-runoff, the default, stored as the empty method.
+	TallyIRV TallyMethod = ""
+	// TallySchulze is the Schulze method, which elects the Condorcet winner
+	// whenever there is one.
+	TallySchulze TallyMethod = "schulze"
+)
+
+// ResultsVisibility is who may see the results of a voting, and when.
+type ResultsVisibility string
+
+const (
+	// ResultsAlways shows the results to everyone at any time; it is the
+	// default and is stored as the empty visibility.
+	ResultsAlways ResultsVisibility = ""
+	// ResultsVoted shows the results to those who voted, and to everyone
+	// once the voting is closed.
+	ResultsVoted ResultsVisibility = "voted"
+	// ResultsCreator shows the results to the creator only, even after the
+	// voting is closed.
+	ResultsCreator ResultsVisibility = "creator"
+	// ResultsClosed hides the results from everyone until the voting is
+	// closed.
+	ResultsClosed ResultsVisibility = "closed"
+)
+
+// VotingSettings holds the options a voting is created with. They are stored
+// as a whole, so a new option only needs a field here, not a schema change.
+type VotingSettings struct {
+	Kind VotingKind `json:"kind,omitempty" msgpack:"kind,omitempty"`
+	// Tally is only used by ranked votings.
+	Tally TallyMethod `json:"tally,omitempty" msgpack:"tally,omitempty"`
+	// MaxChoices is how many options one voter may pick; 0 means one.
+	MaxChoices int `json:"max_choices,omitempty" msgpack:"max_choices,omitempty"`
+	// MinScore and MaxScore bound the scores of a score voting, inclusive.
+	MinScore int `json:"min_score,omitempty" msgpack:"min_score,omitempty"`
+	MaxScore int `json:"max_score,omitempty" msgpack:"max_score,omitempty"`
+	// Weights names the channel weight table ballots are weighted by; empty
+	// means every ballot weighs DefaultWeight.
+	Weights string `json:"weights,omitempty" msgpack:"weights,omitempty"`
+	// Quorum is the share of channel members who must vote for the voting
+	// to count, and Threshold the share of the votes the leading option needs
+	// to pass; both are between 0 and 1, and 0 sets no requirement.
+	Quorum    float64 `json:"quorum,omitempty" msgpack:"quorum,omitempty"`
+	Threshold float64 `json:"threshold,omitempty" msgpack:"threshold,omitempty"`
+	// Anonymous votings store a keyed hash of the voter instead of the user
+	// ID, see Ballot.UserID.
+	Anonymous bool `json:"anonymous,omitempty" msgpack:"anonymous,omitempty"`
+	// Public votings show who voted for what with /poll voters.
+	Public bool `json:"public,omitempty" msgpack:"public,omitempty"`
+	// Results is who may see the results, see Voting.ResultsVisibleTo.
+	Results ResultsVisibility `json:"results,omitempty" msgpack:"results,omitempty"`
+	// Reactions votings are voted with the numbered emoji reactions on the
+	// voting post instead of buttons.
+	Reactions bool `json:"reactions,omitempty" msgpack:"reactions,omitempty"`
+}
+
+// ChoiceLimit is the number of options one voter may pick. Ranked votings
+// are not limited by it: a ranking may hold every option.
+func (s VotingSettings) ChoiceLimit() int {
+	return max(s.MaxChoices, 1)
+}
+
+func (s VotingSettings) IsMultipleChoice() bool {
+	return s.ChoiceLimit() > 1
+}
+
+func (s VotingSettings) IsRanked() bool {
+	return s.Kind == KindRanked
+}
+
+func (s VotingSettings) IsScore() bool {
+	return s.Kind == KindScore
+}
+
+// CountedChoices is how many leading choices of a ballot are kept in the
+// voting's result counters: all of them, only the first preference of a
+// ranking, or none of a score voting, which is summarised from its ballots.
+func (s VotingSettings) CountedChoices() int {
+	switch {
+	case s.IsScore():
+		return 0
+	case s.IsRanked():
+		return 1
+	}
+	return math.MaxInt32
+}
+
+// HasVerdict reports whether the voting has a quorum or a threshold, and so
+// ends in an explicit outcome.
+func (s VotingSettings) HasVerdict() bool {
+	return s.Quorum > 0 || s.Threshold > 0
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// VotingState is a step of the voting lifecycle.
+type VotingState string
+
+const (
+	// StateDraft is a voting visible only to its creator until published.
+	StateDraft VotingState = "draft"
+	// StateScheduled is a voting that opens by itself at OpensAt.
+	StateScheduled VotingState = "scheduled"
+	StateOpen      VotingState = "open"
+	StateClosed    VotingState = "closed"
+	// StateArchived is final: the voting is kept for the record only.
+	StateArchived VotingState = "archived"
+)
+
+// StateTransition records a change of state. The first transition of every
+// voting has an empty From and is its creation.
+type StateTransition struct {
+	From VotingState `json:"from"`
+	To   VotingState `json:"to"`
+	At   time.Time   `json:"at"`
+	// Members is the number of channel members the quorum was counted
+	// against when the voting closed; zero on other transitions and when
+	// they could not be counted.
+	Members int `json:"members,omitempty"`
+}
+
+var allowedTransitions = map[VotingState][]VotingState{
+	StateDraft:     {StateOpen, StateArchived},
+	StateScheduled: {StateOpen, StateArchived},
+	StateOpen:      {StateClosed},
+	StateClosed:    {StateOpen, StateArchived},
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving from s to next.
+func (s VotingState) CanTransitionTo(next VotingState) bool {
+	for _, allowed := range allowedTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s VotingState) IsValid() bool {
+	switch s {
+	case StateDraft, StateScheduled, StateOpen, StateClosed, StateArchived:
+		return true
+	}
+	return false
+}
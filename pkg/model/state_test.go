@@ -0,0 +1,23 @@
+package model
+
+import "testing"
+
+func TestCanTransitionTo(t *testing.T) {
+	states := []VotingState{StateDraft, StateScheduled, StateOpen, StateClosed, StateArchived}
+	allowed := map[[2]VotingState]bool{
+		{StateDraft, StateOpen}:         true,
+		{StateDraft, StateArchived}:     true,
+		{StateScheduled, StateOpen}:     true,
+		{StateScheduled, StateArchived}: true,
+		{StateOpen, StateClosed}:        true,
+		{StateClosed, StateOpen}:        true,
+		{StateClosed, StateArchived}:    true,
+	}
+	for _, from := range states {
+		for _, to := range states {
+			if got := from.CanTransitionTo(to); got != allowed[[2]VotingState{from, to}] {
+				t.Errorf("%s -> %s: got %v, want %v", from, to, got, !got)
+			}
+		}
+	}
+}
@@ -0,0 +1,430 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// VotingTupleVersion is the layout of the votings space tuple written by
+// EncodeMsgpack. Older layouts are recognised by their field count.
+//
+// Version 1:
+//
+//	1 id          string
+//	2 creator_id  string
+//	3 question    string
+//	4 channel_id  string
+//	5 options     array of string
+//	6 created_at  number, seconds since epoch (0 for zero time)
+//	7 closed_at   number, seconds since epoch (0 for zero time)
+//	8 results     array of integer, one counter per option
+//	9 is_active   boolean
+//
+// Version 2 appends:
+//
+//	10 deadline   number, seconds since epoch (0 for no deadline)
+//
+// Version 3 replaces is_active with the lifecycle state and appends:
+//
+//	 9 state        string, see VotingState
+//	11 opens_at     number, seconds since epoch (0 unless scheduled)
+//	12 transitions  array of [from, to, at, members] state transitions;
+//	                older ones lack members
+//
+// Version 4 appends:
+//
+//	13 settings  map, see VotingSettings
+//
+// Version 5 appends:
+//
+//	14 post_id   string, the voting card post ("" until announced)
+const VotingTupleVersion = 5
+
+var votingTupleFields = map[int]int{
+	1: 9,
+	2: 10,
+	3: 12,
+	4: 13,
+	5: 14,
+}
+
+// VotingPostIDField is the number of the post_id field in the current
+// layout, counted from one as above.
+const VotingPostIDField = 14
+
+// BallotTupleVersion is the layout of the ballots space tuple.
+//
+// Version 1:
+//
+//	1 voting_id  string
+//	2 user_id    string
+//	3 option     unsigned, zero-based option index
+//	4 cast_at    number, seconds since epoch
+//
+// Version 2 appends the full selection; option keeps the first choice:
+//
+//	5 choices    array of unsigned, zero-based option indexes
+//
+// Version 3 appends:
+//
+//	6 scores     array of integer, one per choice; empty unless a score voting
+//
+// Version 4 appends:
+//
+//	7 weight     number, what the ballot counted with (1 in older tuples)
+const BallotTupleVersion = 4
+
+var ballotTupleFields = map[int]int{
+	1: 4,
+	2: 5,
+	3: 6,
+	4: 7,
+}
+
+func (v Voting) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if err := enc.EncodeArrayLen(votingTupleFields[VotingTupleVersion]); err != nil {
+		return err
+	}
+	for _, s := range []string{v.ID, v.CreatorID, v.Question, v.ChannelID} {
+		if err := enc.EncodeString(s); err != nil {
+			return err
+		}
+	}
+	if err := encodeStrings(enc, v.Options); err != nil {
+		return err
+	}
+	if err := encodeTime(enc, v.CreatedAt); err != nil {
+		return err
+	}
+	if err := encodeTime(enc, v.ClosedAt); err != nil {
+		return err
+	}
+	if err := enc.EncodeArrayLen(len(v.Options)); err != nil {
+		return err
+	}
+	for i := range v.Options {
+		if err := enc.EncodeInt(int64(v.Results[i])); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeString(string(v.State)); err != nil {
+		return err
+	}
+	if err := encodeTime(enc, v.Deadline); err != nil {
+		return err
+	}
+	if err := encodeTime(enc, v.OpensAt); err != nil {
+		return err
+	}
+	if err := enc.EncodeArrayLen(len(v.Transitions)); err != nil {
+		return err
+	}
+	for _, transition := range v.Transitions {
+		if err := transition.EncodeMsgpack(enc); err != nil {
+			return err
+		}
+	}
+	if err := enc.Encode(v.Settings); err != nil {
+		return err
+	}
+	return enc.EncodeString(v.PostID)
+}
+
+func (v *Voting) DecodeMsgpack(dec *msgpack.Decoder) error {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	version, err := tupleVersion("voting", votingTupleFields, n)
+	if err != nil {
+		return err
+	}
+
+	var voting Voting
+	for _, s := range []*string{&voting.ID, &voting.CreatorID, &voting.Question, &voting.ChannelID} {
+		if *s, err = dec.DecodeString(); err != nil {
+			return err
+		}
+	}
+	if voting.Options, err = decodeStrings(dec); err != nil {
+		return err
+	}
+	if voting.CreatedAt, err = decodeTime(dec); err != nil {
+		return err
+	}
+	if voting.ClosedAt, err = decodeTime(dec); err != nil {
+		return err
+	}
+
+	count, err := dec.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	voting.Results = make(map[int]int, count)
+	for i := 0; i < count; i++ {
+		if voting.Results[i], err = dec.DecodeInt(); err != nil {
+			return err
+		}
+	}
+
+	if version < 3 {
+		// Older layouts only knew whether the voting was active.
+		isActive, err := dec.DecodeBool()
+		if err != nil {
+			return err
+		}
+		voting.State = StateClosed
+		if isActive {
+			voting.State = StateOpen
+		}
+	} else {
+		state, err := dec.DecodeString()
+		if err != nil {
+			return err
+		}
+		voting.State = VotingState(state)
+	}
+	if version >= 2 {
+		if voting.Deadline, err = decodeTime(dec); err != nil {
+			return err
+		}
+	}
+	if version >= 3 {
+		if voting.OpensAt, err = decodeTime(dec); err != nil {
+			return err
+		}
+		count, err := dec.DecodeArrayLen()
+		if err != nil {
+			return err
+		}
+		voting.Transitions = make([]StateTransition, count)
+		for i := range voting.Transitions {
+			if err := voting.Transitions[i].DecodeMsgpack(dec); err != nil {
+				return err
+			}
+		}
+	}
+	if version >= 4 {
+		if err := dec.Decode(&voting.Settings); err != nil {
+			return err
+		}
+	}
+	if version >= 5 {
+		if voting.PostID, err = dec.DecodeString(); err != nil {
+			return err
+		}
+	}
+
+	*v = voting
+	return nil
+}
+
+func (b Ballot) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if len(b.Choices) == 0 {
+		return fmt.Errorf("ballot of user %s has no choices", b.UserID)
+	}
+	if b.Scores != nil && len(b.Scores) != len(b.Choices) {
+		return fmt.Errorf("ballot of user %s has %d scores for %d choices", b.UserID, len(b.Scores), len(b.Choices))
+	}
+	if err := enc.EncodeArrayLen(ballotTupleFields[BallotTupleVersion]); err != nil {
+		return err
+	}
+	if err := enc.EncodeString(b.VotingID); err != nil {
+		return err
+	}
+	if err := enc.EncodeString(b.UserID); err != nil {
+		return err
+	}
+	if err := enc.EncodeUint(uint64(b.Choices[0])); err != nil {
+		return err
+	}
+	if err := encodeTime(enc, b.CastAt); err != nil {
+		return err
+	}
+	if err := enc.EncodeArrayLen(len(b.Choices)); err != nil {
+		return err
+	}
+	for _, choice := range b.Choices {
+		if err := enc.EncodeUint(uint64(choice)); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeArrayLen(len(b.Scores)); err != nil {
+		return err
+	}
+	for _, score := range b.Scores {
+		if err := enc.EncodeInt(int64(score)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeFloat64(b.Weight)
+}
+
+func (b *Ballot) DecodeMsgpack(dec *msgpack.Decoder) error {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	version, err := tupleVersion("ballot", ballotTupleFields, n)
+	if err != nil {
+		return err
+	}
+
+	ballot := Ballot{Weight: DefaultWeight}
+	if ballot.VotingID, err = dec.DecodeString(); err != nil {
+		return err
+	}
+	if ballot.UserID, err = dec.DecodeString(); err != nil {
+		return err
+	}
+	option, err := dec.DecodeInt()
+	if err != nil {
+		return err
+	}
+	if ballot.CastAt, err = decodeTime(dec); err != nil {
+		return err
+	}
+
+	if version < 2 {
+		ballot.Choices = []int{option}
+	} else {
+		count, err := dec.DecodeArrayLen()
+		if err != nil {
+			return err
+		}
+		ballot.Choices = make([]int, count)
+		for i := range ballot.Choices {
+			if ballot.Choices[i], err = dec.DecodeInt(); err != nil {
+				return err
+			}
+		}
+	}
+	if version >= 3 {
+		count, err := dec.DecodeArrayLen()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			ballot.Scores = make([]int, count)
+		}
+		for i := range ballot.Scores {
+			if ballot.Scores[i], err = dec.DecodeInt(); err != nil {
+				return err
+			}
+		}
+	}
+	if version >= 4 {
+		if ballot.Weight, err = dec.DecodeFloat64(); err != nil {
+			return err
+		}
+	}
+
+	*b = ballot
+	return nil
+}
+
+func (t StateTransition) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if err := enc.EncodeArrayLen(4); err != nil {
+		return err
+	}
+	if err := enc.EncodeString(string(t.From)); err != nil {
+		return err
+	}
+	if err := enc.EncodeString(string(t.To)); err != nil {
+		return err
+	}
+	if err := encodeTime(enc, t.At); err != nil {
+		return err
+	}
+	return enc.EncodeInt(int64(t.Members))
+}
+
+func (t *StateTransition) DecodeMsgpack(dec *msgpack.Decoder) error {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	if n != 3 && n != 4 {
+		return fmt.Errorf("unknown state transition layout with %d fields", n)
+	}
+
+	var transition StateTransition
+	from, err := dec.DecodeString()
+	if err != nil {
+		return err
+	}
+	to, err := dec.DecodeString()
+	if err != nil {
+		return err
+	}
+	transition.From, transition.To = VotingState(from), VotingState(to)
+	if transition.At, err = decodeTime(dec); err != nil {
+		return err
+	}
+	if n == 4 {
+		if transition.Members, err = dec.DecodeInt(); err != nil {
+			return err
+		}
+	}
+
+	*t = transition
+	return nil
+}
+
+func tupleVersion(name string, fields map[int]int, n int) (int, error) {
+	for version, count := range fields {
+		if count == n {
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s tuple layout with %d fields", name, n)
+}
+
+func encodeStrings(enc *msgpack.Encoder, values []string) error {
+	if err := enc.EncodeArrayLen(len(values)); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := enc.EncodeString(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeStrings(dec *msgpack.Decoder) ([]string, error) {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		value, err := dec.DecodeString()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Timestamps are stored with second precision, the zero time as 0.
+func encodeTime(enc *msgpack.Encoder, t time.Time) error {
+	if t.IsZero() {
+		return enc.EncodeInt(0)
+	}
+	return enc.EncodeInt(t.Unix())
+}
+
+func decodeTime(dec *msgpack.Decoder) (time.Time, error) {
+	seconds, err := dec.DecodeFloat64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if seconds == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(int64(seconds), 0), nil
+}
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// encodeTuple writes fields as one msgpack array, the way Tarantool returns
+// a tuple of an older layout.
+func encodeTuple(t *testing.T, fields ...interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	if err := enc.EncodeArrayLen(len(fields)); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range fields {
+		if err := enc.Encode(field); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestVotingDecodeLegacyLayouts(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	closed := time.Unix(1700003600, 0)
+	deadline := time.Unix(1700007200, 0)
+	opens := time.Unix(1699990000, 0)
+	v1 := []interface{}{"v1", "creator", "Столица?", "channel", []string{"Мемфис", "Нэшвилл"},
+		created.Unix(), closed.Unix(), []int{3, 5}, false}
+
+	tests := []struct {
+		name   string
+		fields []interface{}
+		want   Voting
+	}{
+		{
+			name:   "v1",
+			fields: v1,
+			want: Voting{ID: "v1", CreatorID: "creator", Question: "Столица?", ChannelID: "channel",
+				Options: []string{"Мемфис", "Нэшвилл"}, CreatedAt: created, ClosedAt: closed,
+				Results: map[int]int{0: 3, 1: 5}, State: StateClosed},
+		},
+		{
+			name:   "v2",
+			fields: append(append([]interface{}{}, v1[:8]...), true, deadline.Unix()),
+			want: Voting{ID: "v1", CreatorID: "creator", Question: "Столица?", ChannelID: "channel",
+				Options: []string{"Мемфис", "Нэшвилл"}, CreatedAt: created, ClosedAt: closed,
+				Results: map[int]int{0: 3, 1: 5}, State: StateOpen, Deadline: deadline},
+		},
+		{
+			name: "v3",
+			fields: append(append([]interface{}{}, v1[:8]...), string(StateOpen), 0, opens.Unix(),
+				[]interface{}{[]interface{}{string(StateScheduled), string(StateOpen), opens.Unix()}}),
+			want: Voting{ID: "v1", CreatorID: "creator", Question: "Столица?", ChannelID: "channel",
+				Options: []string{"Мемфис", "Нэшвилл"}, CreatedAt: created, ClosedAt: closed,
+				Results: map[int]int{0: 3, 1: 5}, State: StateOpen, OpensAt: opens,
+				Transitions: []StateTransition{{From: StateScheduled, To: StateOpen, At: opens}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Voting
+			if err := msgpack.Unmarshal(encodeTuple(t, tt.fields...), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVotingRoundTrip(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	want := Voting{
+		ID: "id", CreatorID: "creator", Question: "Обед?", ChannelID: "channel",
+		Options:   []string{"Пицца", "Суши", "Борщ"},
+		CreatedAt: at, Deadline: at.Add(time.Hour), OpensAt: at.Add(-time.Hour),
+		// Counters are encoded signed, so even a negative one survives.
+		Results: map[int]int{0: 2, 1: 0, 2: -1},
+		State:   StateOpen,
+		Transitions: []StateTransition{
+			{From: StateDraft, To: StateScheduled, At: at.Add(-2 * time.Hour)},
+			{From: StateScheduled, To: StateOpen, At: at.Add(-time.Hour)},
+			{From: StateOpen, To: StateClosed, At: at.Add(-time.Minute), Members: 7},
+			{From: StateClosed, To: StateOpen, At: at},
+		},
+		Settings: VotingSettings{Kind: KindRanked, Results: ResultsVoted, Anonymous: true},
+		PostID:   "post",
+	}
+
+	data, err := msgpack.Marshal(want)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var got Voting
+	if err := msgpack.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip gave %+v, want %+v", got, want)
+	}
+}
+
+func TestBallotDecodeLegacyLayouts(t *testing.T) {
+	cast := time.Unix(1700000000, 0)
+	tests := []struct {
+		name   string
+		fields []interface{}
+		want   Ballot
+	}{
+		{
+			name:   "v1",
+			fields: []interface{}{"voting", "user", 2, cast.Unix()},
+			want:   Ballot{VotingID: "voting", UserID: "user", Choices: []int{2}, Weight: DefaultWeight, CastAt: cast},
+		},
+		{
+			name:   "v2",
+			fields: []interface{}{"voting", "user", 2, cast.Unix(), []int{2, 0}},
+			want:   Ballot{VotingID: "voting", UserID: "user", Choices: []int{2, 0}, Weight: DefaultWeight, CastAt: cast},
+		},
+		{
+			name:   "v3",
+			fields: []interface{}{"voting", "user", 2, cast.Unix(), []int{2, 0}, []int{5, -3}},
+			want: Ballot{VotingID: "voting", UserID: "user", Choices: []int{2, 0}, Scores: []int{5, -3},
+				Weight: DefaultWeight, CastAt: cast},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Ballot
+			if err := msgpack.Unmarshal(encodeTuple(t, tt.fields...), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBallotRoundTrip(t *testing.T) {
+	want := Ballot{VotingID: "voting", UserID: "user", Choices: []int{1, 0}, Scores: []int{-2, 4},
+		Weight: 2.5, CastAt: time.Unix(1700000000, 0)}
+
+	data, err := msgpack.Marshal(want)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var got Ballot
+	if err := msgpack.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip gave %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeUnknownLayout(t *testing.T) {
+	var voting Voting
+	if err := msgpack.Unmarshal(encodeTuple(t, "id", "creator"), &voting); err == nil {
+		t.Error("decoded a voting tuple of 2 fields")
+	}
+	var ballot Ballot
+	if err := msgpack.Unmarshal(encodeTuple(t, "voting"), &ballot); err == nil {
+		t.Error("decoded a ballot tuple of 1 field")
+	}
+}
+
+// The repository updates post_id in place by its field number.
+func TestVotingPostIDField(t *testing.T) {
+	data, err := msgpack.Marshal(Voting{ID: "v", Options: []string{"Да", "Нет"}, Results: map[int]int{}, PostID: "post"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []interface{}
+	if err := msgpack.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) < VotingPostIDField || fields[VotingPostIDField-1] != "post" {
+		t.Errorf("field %d of %v is not post_id", VotingPostIDField, fields)
+	}
+}
@@ -11,5 +11,64 @@ type Voting struct {
 	CreatedAt time.Time   `json:"created_at"`
 	ClosedAt  time.Time   `json:"closed_at"`
 	Results   map[int]int `json:"results"`
-	IsActive  bool
+	State     VotingState `json:"state"`
+	// Deadline is when the voting closes by itself; zero means never.
+	Deadline time.Time `json:"deadline"`
+	// OpensAt is when a scheduled voting opens by itself.
+	OpensAt     time.Time         `json:"opens_at"`
+	Transitions []StateTransition `json:"transitions"`
+	Settings    VotingSettings    `json:"settings"`
+	// PostID is the Mattermost post of the voting card, kept up to date
+	// with the tallies; empty until the voting is announced.
+	PostID string `json:"post_id,omitempty"`
+}
+
+func (v Voting) IsOpen() bool {
+	return v.State == StateOpen
+}
+
+// IsFinished reports whether the voting is closed or archived.
+func (v Voting) IsFinished() bool {
+	return v.State == StateClosed || v.State == StateArchived
+}
+
+// ClosedMembers is the number of channel members recorded when the voting
+// was last closed, or zero when none was.
+func (v Voting) ClosedMembers() int {
+	for i := len(v.Transitions) - 1; i >= 0; i-- {
+		if v.Transitions[i].To == StateClosed {
+			return v.Transitions[i].Members
+		}
+	}
+	return 0
+}
+
+// ResultsPublic reports whether the results may be shown to everyone, such
+// as in the voting's channel.
+func (v Voting) ResultsPublic() bool {
+	switch v.Settings.Results {
+	case ResultsAlways:
+		return true
+	case ResultsCreator:
+		return false
+	default:
+		return v.IsFinished()
+	}
+}
+
+// ResultsVisibleTo reports whether the user may see the results; voted tells
+// whether they have voted. The creator may see them unless they are hidden
+// until the voting closes.
+func (v Voting) ResultsVisibleTo(userID string, voted bool) bool {
+	if v.ResultsPublic() {
+		return true
+	}
+	switch v.Settings.Results {
+	case ResultsVoted:
+		return voted || userID == v.CreatorID
+	case ResultsCreator:
+		return userID == v.CreatorID
+	default:
+		return false
+	}
 }
@@ -0,0 +1,28 @@
+package model
+
+// WeightSubject is what a weight table entry applies to.
+type WeightSubject string
+
+const (
+	WeightUser WeightSubject = "user"
+	// WeightRole entries match Mattermost system and channel roles, such as
+	// channel_admin.
+	WeightRole  WeightSubject = "role"
+	WeightGroup WeightSubject = "group"
+)
+
+// WeightEntry gives the ballots of a user, or of everyone with a role or in
+// a group, a weight. Weight tables are named and belong to a channel.
+type WeightEntry struct {
+	_msgpack  struct{}      `msgpack:",asArray"`
+	ChannelID string        `json:"channel_id"`
+	Table     string        `json:"table"`
+	Subject   WeightSubject `json:"subject"`
+	// Name is the user ID, the role name or the group name.
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+}
+
+// DefaultWeight is the weight of a voter no entry of the table matches, and
+// of every voter in a voting without a weight table.
+const DefaultWeight = 1.0
@@ -0,0 +1,20 @@
+package repository
+
+// resultDeltas is how the result counters move when a voter's ballot changes
+// from previous to next choices; counted is how many leading choices of a
+// ballot count, see model.VotingSettings.CountedChoices.
+func resultDeltas(previous, next []int, counted int) map[int]int {
+	deltas := make(map[int]int)
+	for _, option := range previous[:min(len(previous), counted)] {
+		deltas[option]--
+	}
+	for _, option := range next[:min(len(next), counted)] {
+		deltas[option]++
+	}
+	for option, delta := range deltas {
+		if delta == 0 {
+			delete(deltas, option)
+		}
+	}
+	return deltas
+}
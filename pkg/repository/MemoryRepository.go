@@ -0,0 +1,368 @@
+package repository
+
+import (
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/utils"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryVotingRepository keeps votings in process memory. It mirrors the
+// semantics of the Tarantool repository and is meant for local runs and tests.
+type memoryVotingRepository struct {
+	mu      sync.RWMutex
+	votings map[string]model.Voting
+	ballots map[string]map[string]model.Ballot
+	weights map[string][]model.WeightEntry
+}
+
+func NewMemoryRepository() *memoryVotingRepository {
+	return &memoryVotingRepository{
+		votings: make(map[string]model.Voting),
+		ballots: make(map[string]map[string]model.Ballot),
+		weights: make(map[string][]model.WeightEntry),
+	}
+}
+
+func (m *memoryVotingRepository) SaveVoting(voting model.Voting) (model.Voting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.votings[voting.ID]; ok {
+		err := errors.NotSaved.New(errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, voting.ID, "can't save record with this id")
+		return model.Voting{}, err
+	}
+	m.votings[voting.ID] = cloneVoting(voting)
+	return voting, nil
+}
+
+func (m *memoryVotingRepository) GetVoting(votingID string) (model.Voting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	voting, ok := m.votings[votingID]
+	if !ok {
+		return model.Voting{}, votingNotFound(votingID)
+	}
+	return cloneVoting(voting), nil
+}
+
+func (m *memoryVotingRepository) GetVotingByPost(postID string) (model.Voting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, voting := range m.votings {
+		if postID != "" && voting.PostID == postID {
+			return cloneVoting(voting), nil
+		}
+	}
+	return model.Voting{}, votingNotFound(postID)
+}
+
+func (m *memoryVotingRepository) DeleteVoting(votingID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.votings, votingID)
+	delete(m.ballots, votingID)
+	return votingID, nil
+}
+
+func (m *memoryVotingRepository) TransitionVoting(votingID string, transition model.StateTransition, deadline time.Time) (model.Voting, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	voting, ok := m.votings[votingID]
+	if !ok {
+		return model.Voting{}, votingNotFound(votingID)
+	}
+	if voting.State != transition.From {
+		return model.Voting{}, stateConflict(votingID, voting.State, transition)
+	}
+
+	transition.At = transition.At.Truncate(time.Second)
+	voting = cloneVoting(voting)
+	voting.State = transition.To
+	voting.Deadline = deadline.Truncate(time.Second)
+	if transition.To == model.StateClosed {
+		voting.ClosedAt = transition.At
+	}
+	voting.Transitions = append(voting.Transitions, transition)
+	m.votings[votingID] = voting
+	return cloneVoting(voting), nil
+}
+
+func (m *memoryVotingRepository) SetVotingPost(votingID, postID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	voting, ok := m.votings[votingID]
+	if !ok {
+		return votingNotFound(votingID)
+	}
+	voting.PostID = postID
+	m.votings[votingID] = voting
+	return nil
+}
+
+func (m *memoryVotingRepository) ReplaceBallot(ballot model.Ballot, counted int) (model.Ballot, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	voting, ok := m.votings[ballot.VotingID]
+	if !ok {
+		return model.Ballot{}, false, votingNotFound(ballot.VotingID)
+	}
+	ballots, ok := m.ballots[ballot.VotingID]
+	if !ok {
+		ballots = make(map[string]model.Ballot)
+		m.ballots[ballot.VotingID] = ballots
+	}
+	previous, replaced := ballots[ballot.UserID]
+	ballots[ballot.UserID] = cloneBallot(ballot)
+	for option, delta := range resultDeltas(previous.Choices, ballot.Choices, counted) {
+		voting.Results[option] += delta
+	}
+	return previous, replaced, nil
+}
+
+func (m *memoryVotingRepository) GetBallot(votingID, userID string) (model.Ballot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ballot, ok := m.ballots[votingID][userID]
+	if !ok {
+		return model.Ballot{}, ballotNotFound(userID)
+	}
+	return cloneBallot(ballot), nil
+}
+
+func (m *memoryVotingRepository) GetBallots(votingID string) ([]model.Ballot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ballots := make([]model.Ballot, 0, len(m.ballots[votingID]))
+	for _, ballot := range m.ballots[votingID] {
+		ballots = append(ballots, cloneBallot(ballot))
+	}
+	sort.Slice(ballots, func(i, j int) bool { return ballots[i].UserID < ballots[j].UserID })
+	return ballots, nil
+}
+
+func (m *memoryVotingRepository) CountBallots(votingIDs []string) (map[string]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int, len(votingIDs))
+	for _, votingID := range votingIDs {
+		if n := len(m.ballots[votingID]); n > 0 {
+			counts[votingID] = n
+		}
+	}
+	return counts, nil
+}
+
+func (m *memoryVotingRepository) DeleteBallot(votingID, userID string, counted int) (model.Ballot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ballot, ok := m.ballots[votingID][userID]
+	if !ok {
+		return model.Ballot{}, ballotNotFound(userID)
+	}
+	delete(m.ballots[votingID], userID)
+	if voting, ok := m.votings[votingID]; ok {
+		for option, delta := range resultDeltas(ballot.Choices, nil, counted) {
+			voting.Results[option] += delta
+		}
+	}
+	return ballot, nil
+}
+
+func (m *memoryVotingRepository) ListVotingsByChannel(channelID string, state model.VotingState, offset, limit int) ([]model.Voting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var votings []model.Voting
+	for _, voting := range m.votings {
+		if voting.ChannelID != channelID || (state != "" && voting.State != state) {
+			continue
+		}
+		votings = append(votings, cloneVoting(voting))
+	}
+	sort.Slice(votings, func(i, j int) bool {
+		if !votings[i].CreatedAt.Equal(votings[j].CreatedAt) {
+			return votings[i].CreatedAt.After(votings[j].CreatedAt)
+		}
+		return votings[i].ID > votings[j].ID
+	})
+
+	if offset >= len(votings) {
+		return nil, nil
+	}
+	return votings[offset:min(offset+limit, len(votings))], nil
+}
+
+func (m *memoryVotingRepository) FindVotingByPrefix(prefix string) (model.Voting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var votings []model.Voting
+	for id, voting := range m.votings {
+		if strings.HasPrefix(id, prefix) {
+			votings = append(votings, cloneVoting(voting))
+		}
+	}
+	return singleVotingWithPrefix(votings, prefix)
+}
+
+func (m *memoryVotingRepository) SearchVotings(search VotingSearch) ([]model.Voting, error) {
+	m.mu.RLock()
+	terms := make(map[string]map[string]int, len(m.votings))
+	channels := make(map[string]string, len(m.votings))
+	for id, voting := range m.votings {
+		if search.matches(voting.State, voting.ChannelID) {
+			terms[id] = utils.SearchTerms(voting.Question, voting.Options)
+			channels[id] = voting.ChannelID
+		}
+	}
+	m.mu.RUnlock()
+
+	candidates, err := rankSearchCandidates(search, func(prefix string) (map[string]searchHit, error) {
+		hits := make(map[string]searchHit)
+		for votingID, votingTerms := range terms {
+			for term, weight := range votingTerms {
+				if strings.HasPrefix(term, prefix) {
+					hit := hits[votingID]
+					hits[votingID] = searchHit{ChannelID: channels[votingID], Weight: hit.Weight + weight}
+				}
+			}
+		}
+		return hits, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collectSearchResults(search, candidates, m.getVotings)
+}
+
+func (m *memoryVotingRepository) getVotings(votingIDs []string) ([]model.Voting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var votings []model.Voting
+	for _, id := range votingIDs {
+		if voting, ok := m.votings[id]; ok {
+			votings = append(votings, cloneVoting(voting))
+		}
+	}
+	return votings, nil
+}
+
+func (m *memoryVotingRepository) ListPendingVotings() ([]model.Voting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var votings []model.Voting
+	for _, voting := range m.votings {
+		if voting.State == model.StateScheduled || (voting.State == model.StateOpen && !voting.Deadline.IsZero()) {
+			votings = append(votings, cloneVoting(voting))
+		}
+	}
+	sort.Slice(votings, func(i, j int) bool { return votings[i].ID < votings[j].ID })
+	return votings, nil
+}
+
+func (m *memoryVotingRepository) SetWeight(entry model.WeightEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := slices.DeleteFunc(m.weights[entry.ChannelID], func(e model.WeightEntry) bool {
+		return e.Table == entry.Table && e.Subject == entry.Subject && e.Name == entry.Name
+	})
+	entries = append(entries, entry)
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		return a.Name < b.Name
+	})
+	m.weights[entry.ChannelID] = entries
+	return nil
+}
+
+func (m *memoryVotingRepository) GetWeightTable(channelID, table string) ([]model.WeightEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []model.WeightEntry
+	for _, entry := range m.weights[channelID] {
+		if entry.Table == table {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (m *memoryVotingRepository) ListWeightTables(channelID string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return weightTableNames(m.weights[channelID]), nil
+}
+
+// cloneVoting copies a voting so callers never share its slices and maps
+// with the store, and applies the same normalisation as the Tarantool
+// tuple codec: second-precision timestamps and one counter per option.
+func cloneVoting(voting model.Voting) model.Voting {
+	voting.Options = slices.Clone(voting.Options)
+	voting.CreatedAt = voting.CreatedAt.Truncate(time.Second)
+	voting.ClosedAt = voting.ClosedAt.Truncate(time.Second)
+	voting.Deadline = voting.Deadline.Truncate(time.Second)
+	voting.OpensAt = voting.OpensAt.Truncate(time.Second)
+
+	transitions := make([]model.StateTransition, len(voting.Transitions))
+	for i, transition := range voting.Transitions {
+		transition.At = transition.At.Truncate(time.Second)
+		transitions[i] = transition
+	}
+	voting.Transitions = transitions
+
+	results := make(map[int]int, len(voting.Options))
+	for i := range voting.Options {
+		results[i] = voting.Results[i]
+	}
+	voting.Results = results
+	return voting
+}
+
+func cloneBallot(ballot model.Ballot) model.Ballot {
+	ballot.Choices = slices.Clone(ballot.Choices)
+	ballot.Scores = slices.Clone(ballot.Scores)
+	ballot.CastAt = ballot.CastAt.Truncate(time.Second)
+	return ballot
+}
+
+func votingNotFound(votingID string) error {
+	err := errors.NotFound.New(errors.NotFound.Message())
+	return errors.AddErrorContext(err, votingID, "Voting not found for ID")
+}
+
+func stateConflict(votingID string, state model.VotingState, transition model.StateTransition) error {
+	err := errors.InvalidTransition.Newf("voting is %s, can't move it from %s to %s", state, transition.From, transition.To)
+	return errors.AddErrorContext(err, votingID, "Voting state changed concurrently")
+}
+
+func ballotNotFound(userID string) error {
+	err := errors.NotFound.New(errors.NotFound.Message())
+	return errors.AddErrorContext(err, userID, "Ballot not found for user")
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"go-voting-bot/pkg/utils"
+	"log/slog"
+	"time"
+)
+
+type sqlMigration struct {
+	Version    int
+	Name       string
+	Statements []string
+	// Backfill, when set, runs after Statements in the same transaction to
+	// convert existing data in Go.
+	Backfill func(r *sqlVotingRepository, tx *sql.Tx) error
+}
+
+// Migrations are applied in order, each in its own transaction, and must
+// never be edited once released: add a new entry instead. Statements have to
+// work on both SQLite and PostgreSQL.
+var sqlMigrations = []sqlMigration{
+	{
+		Version: 1,
+		Name:    "create votings, options and ballots tables",
+		Statements: []string{
+			`CREATE TABLE votings (
+				id         TEXT PRIMARY KEY,
+				creator_id TEXT NOT NULL,
+				question   TEXT NOT NULL,
+				channel_id TEXT NOT NULL,
+				created_at BIGINT NOT NULL,
+				closed_at  BIGINT NOT NULL DEFAULT 0,
+				is_active  BOOLEAN NOT NULL
+			)`,
+			`CREATE INDEX votings_channel_id ON votings (channel_id)`,
+			`CREATE TABLE voting_options (
+				voting_id TEXT NOT NULL REFERENCES votings (id) ON DELETE CASCADE,
+				position  INTEGER NOT NULL,
+				label     TEXT NOT NULL,
+				votes     INTEGER NOT NULL DEFAULT 0 CHECK (votes >= 0),
+				PRIMARY KEY (voting_id, position)
+			)`,
+			`CREATE TABLE ballots (
+				voting_id       TEXT NOT NULL REFERENCES votings (id) ON DELETE CASCADE,
+				user_id         TEXT NOT NULL,
+				option_position INTEGER NOT NULL,
+				cast_at         BIGINT NOT NULL,
+				PRIMARY KEY (voting_id, user_id)
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "index votings by channel, status and creation time",
+		Statements: []string{
+			`DROP INDEX votings_channel_id`,
+			`CREATE INDEX votings_channel_created ON votings (channel_id, created_at)`,
+			`CREATE INDEX votings_channel_status ON votings (channel_id, is_active, created_at)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create search terms table",
+		Statements: []string{
+			`CREATE TABLE voting_terms (
+				term      TEXT NOT NULL,
+				voting_id TEXT NOT NULL REFERENCES votings (id) ON DELETE CASCADE,
+				weight    INTEGER NOT NULL,
+				PRIMARY KEY (term, voting_id)
+			)`,
+			`CREATE INDEX voting_terms_voting_id ON voting_terms (voting_id)`,
+		},
+		Backfill: func(r *sqlVotingRepository, tx *sql.Tx) error {
+			rows, err := tx.Query(`SELECT v.id, v.question, o.label FROM votings v
+				JOIN voting_options o ON o.voting_id = v.id ORDER BY v.id, o.position`)
+			if err != nil {
+				return err
+			}
+			questions := make(map[string]string)
+			options := make(map[string][]string)
+			for rows.Next() {
+				var id, question, label string
+				if err := rows.Scan(&id, &question, &label); err != nil {
+					rows.Close()
+					return err
+				}
+				questions[id] = question
+				options[id] = append(options[id], label)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			for id, question := range questions {
+				if err := r.setTerms(tx, id, utils.SearchTerms(question, options[id])); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add voting deadlines",
+		Statements: []string{
+			`ALTER TABLE votings ADD COLUMN deadline BIGINT NOT NULL DEFAULT 0`,
+			`CREATE INDEX votings_deadline ON votings (is_active, deadline)`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "replace is_active with lifecycle state",
+		Statements: []string{
+			`ALTER TABLE votings ADD COLUMN state TEXT NOT NULL DEFAULT 'open'`,
+			`ALTER TABLE votings ADD COLUMN opens_at BIGINT NOT NULL DEFAULT 0`,
+			`UPDATE votings SET state = CASE WHEN is_active THEN 'open' ELSE 'closed' END`,
+			`CREATE TABLE voting_transitions (
+				voting_id  TEXT NOT NULL REFERENCES votings (id) ON DELETE CASCADE,
+				seq        INTEGER NOT NULL,
+				from_state TEXT NOT NULL,
+				to_state   TEXT NOT NULL,
+				at         BIGINT NOT NULL,
+				PRIMARY KEY (voting_id, seq)
+			)`,
+			`INSERT INTO voting_transitions (voting_id, seq, from_state, to_state, at)
+				SELECT id, 1, '', 'open', created_at FROM votings`,
+			`INSERT INTO voting_transitions (voting_id, seq, from_state, to_state, at)
+				SELECT id, 2, 'open', 'closed', closed_at FROM votings WHERE NOT is_active`,
+			`DROP INDEX votings_channel_status`,
+			`DROP INDEX votings_deadline`,
+			`ALTER TABLE votings DROP COLUMN is_active`,
+			`CREATE INDEX votings_channel_state ON votings (channel_id, state, created_at)`,
+			`CREATE INDEX votings_state_deadline ON votings (state, deadline)`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add voting settings and multiple ballot choices",
+		Statements: []string{
+			`ALTER TABLE votings ADD COLUMN settings TEXT NOT NULL DEFAULT '{}'`,
+			`CREATE TABLE ballot_choices (
+				voting_id       TEXT NOT NULL,
+				user_id         TEXT NOT NULL,
+				seq             INTEGER NOT NULL,
+				option_position INTEGER NOT NULL,
+				PRIMARY KEY (voting_id, user_id, seq),
+				FOREIGN KEY (voting_id, user_id) REFERENCES ballots (voting_id, user_id) ON DELETE CASCADE
+			)`,
+			`INSERT INTO ballot_choices (voting_id, user_id, seq, option_position)
+				SELECT voting_id, user_id, 1, option_position FROM ballots`,
+		},
+	},
+	{
+		Version:    7,
+		Name:       "add ballot scores",
+		Statements: []string{`ALTER TABLE ballot_choices ADD COLUMN score INTEGER`},
+	},
+	{
+		Version: 8,
+		Name:    "add weight tables and ballot weights",
+		Statements: []string{
+			`CREATE TABLE weight_entries (
+				channel_id TEXT NOT NULL,
+				table_name TEXT NOT NULL,
+				subject    TEXT NOT NULL,
+				name       TEXT NOT NULL,
+				weight     DOUBLE PRECISION NOT NULL,
+				PRIMARY KEY (channel_id, table_name, subject, name)
+			)`,
+			`ALTER TABLE ballots ADD COLUMN weight DOUBLE PRECISION NOT NULL DEFAULT 1`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add voting card post ids",
+		Statements: []string{
+			`ALTER TABLE votings ADD COLUMN post_id TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "index votings by card post",
+		Statements: []string{
+			`CREATE INDEX votings_post_id ON votings (post_id)`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "record channel members on closing",
+		Statements: []string{
+			`ALTER TABLE voting_transitions ADD COLUMN members INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+}
+
+// Migrate brings the SQL schema up to the latest version known to this
+// binary. It refuses to run against a schema that is newer than that.
+func (r *sqlVotingRepository) Migrate() error {
+	_, err := r.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var current int
+	if err := r.DB.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	latest := sqlMigrations[len(sqlMigrations)-1].Version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, latest)
+	}
+
+	for _, migration := range sqlMigrations {
+		if migration.Version <= current {
+			continue
+		}
+
+		r.Logger.Info("Applying migration", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+		if err := r.applyMigration(migration); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	r.Logger.Info("Database schema is up to date", slog.Int("version", latest))
+	return nil
+}
+
+func (r *sqlVotingRepository) applyMigration(migration sqlMigration) error {
+	return r.inTx(func(tx *sql.Tx) error {
+		for _, statement := range migration.Statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		if migration.Backfill != nil {
+			if err := migration.Backfill(r, tx); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(r.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+			migration.Version, migration.Name, time.Now().Unix())
+		return err
+	})
+}
@@ -0,0 +1,871 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/utils"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// sqlVotingRepository stores votings in SQLite or PostgreSQL. Queries are
+// written with "?" placeholders and rebound for PostgreSQL.
+type sqlVotingRepository struct {
+	DB     *sql.DB
+	Logger *slog.Logger
+	driver string
+}
+
+func NewSQLRepository(driver string, dsn string, logger *slog.Logger) (*sqlVotingRepository, error) {
+	var db *sql.DB
+	var err error
+	switch driver {
+	case DriverSQLite:
+		db, err = sql.Open("sqlite", sqliteDSN(dsn))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+		// SQLite allows a single writer; one connection keeps transactions serialised.
+		db.SetMaxOpenConns(1)
+	case DriverPostgres:
+		db, err = sql.Open("pgx", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open PostgreSQL database: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown SQL driver %q", driver)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", driver, err)
+	}
+
+	return &sqlVotingRepository{DB: db, Logger: logger, driver: driver}, nil
+}
+
+// sqliteDSN enables foreign keys in the DSN: the pragma is per connection,
+// and the pool may open new connections at any time.
+func sqliteDSN(dsn string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=foreign_keys(1)"
+}
+
+func (r *sqlVotingRepository) Close() error {
+	return r.DB.Close()
+}
+
+func (r *sqlVotingRepository) SaveVoting(voting model.Voting) (model.Voting, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(r.rebind(`INSERT INTO votings (id, creator_id, question, channel_id, created_at, closed_at, state, deadline, opens_at, settings, post_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			voting.ID, voting.CreatorID, voting.Question, voting.ChannelID,
+			unixTime(voting.CreatedAt), unixTime(voting.ClosedAt), string(voting.State),
+			unixTime(voting.Deadline), unixTime(voting.OpensAt), settingsJSON(voting.Settings), voting.PostID)
+		if err != nil {
+			return err
+		}
+		if err := r.setTerms(tx, voting.ID, utils.SearchTerms(voting.Question, voting.Options)); err != nil {
+			return err
+		}
+		if err := r.insertTransitions(tx, voting); err != nil {
+			return err
+		}
+		return r.insertOptions(tx, voting)
+	})
+	if err != nil {
+		r.Logger.Error("can't save record with this id", slog.String("id", voting.ID))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, voting.ID, "can't save record with this id")
+		return model.Voting{}, err
+	}
+	return voting, nil
+}
+
+const votingColumns = `id, creator_id, question, channel_id, created_at, closed_at, state, deadline, opens_at, settings, post_id`
+
+func (r *sqlVotingRepository) GetVoting(votingID string) (model.Voting, error) {
+	voting, err := scanVoting(r.DB.QueryRow(r.rebind(`SELECT `+votingColumns+` FROM votings WHERE id = ?`), votingID))
+	if err == sql.ErrNoRows {
+		r.Logger.Error("Voting not found for ID", slog.String("id", votingID))
+		return model.Voting{}, votingNotFound(votingID)
+	}
+	if err != nil {
+		r.Logger.Error("Failed to get voting from database", slog.String("id", votingID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to get voting from database")
+		return model.Voting{}, err
+	}
+
+	if err := r.loadOptions(&voting); err != nil {
+		return model.Voting{}, err
+	}
+	if err := r.loadTransitions(&voting); err != nil {
+		return model.Voting{}, err
+	}
+	return voting, nil
+}
+
+// GetVotingByPost finds the voting whose card is the post. Most posts have
+// no voting, so a miss is not logged.
+func (r *sqlVotingRepository) GetVotingByPost(postID string) (model.Voting, error) {
+	if postID == "" {
+		return model.Voting{}, votingNotFound(postID)
+	}
+	var votingID string
+	err := r.DB.QueryRow(r.rebind(`SELECT id FROM votings WHERE post_id = ?`), postID).Scan(&votingID)
+	if err == sql.ErrNoRows {
+		return model.Voting{}, votingNotFound(postID)
+	}
+	if err != nil {
+		r.Logger.Error("Failed to get voting from database", slog.String("post_id", postID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, postID, "Failed to get voting from database")
+		return model.Voting{}, err
+	}
+	return r.GetVoting(votingID)
+}
+
+// ListVotingsByChannel returns votings of a channel in the given state, or in
+// any state when it is empty, newest first.
+func (r *sqlVotingRepository) ListVotingsByChannel(channelID string, state model.VotingState, offset, limit int) ([]model.Voting, error) {
+	query := `SELECT ` + votingColumns + ` FROM votings WHERE channel_id = ?`
+	args := []interface{}{channelID}
+	if state != "" {
+		query += ` AND state = ?`
+		args = append(args, string(state))
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	votings, err := r.queryVotings(query, args...)
+	if err != nil {
+		r.Logger.Error("Failed to list votings from database", slog.String("channel_id", channelID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, channelID, "Failed to list votings from database")
+		return nil, err
+	}
+	return votings, nil
+}
+
+// FindVotingByPrefix returns the only voting whose ID starts with prefix.
+func (r *sqlVotingRepository) FindVotingByPrefix(prefix string) (model.Voting, error) {
+	votings, err := r.queryVotings(`SELECT `+votingColumns+` FROM votings WHERE id >= ? ORDER BY id LIMIT 2`, prefix)
+	if err != nil {
+		r.Logger.Error("Failed to get voting from database", slog.String("prefix", prefix))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, prefix, "Failed to get voting from database")
+		return model.Voting{}, err
+	}
+	return singleVotingWithPrefix(votings, prefix)
+}
+
+func (r *sqlVotingRepository) SearchVotings(search VotingSearch) ([]model.Voting, error) {
+	states := search.states()
+	candidates, err := rankSearchCandidates(search, func(prefix string) (map[string]searchHit, error) {
+		// Terms hold only letters and digits, so the prefix needs no LIKE escaping.
+		query := `SELECT t.voting_id, t.weight, v.channel_id FROM voting_terms t
+			JOIN votings v ON v.id = t.voting_id
+			WHERE t.term LIKE ? AND v.state IN (` + placeholders(len(states)) + `)`
+		args := []interface{}{prefix + "%"}
+		for _, state := range states {
+			args = append(args, state)
+		}
+		if len(search.ChannelIDs) > 0 {
+			query += ` AND v.channel_id IN (` + placeholders(len(search.ChannelIDs)) + `)`
+			for _, channelID := range search.ChannelIDs {
+				args = append(args, channelID)
+			}
+		}
+		if len(search.ExcludeChannelIDs) > 0 {
+			query += ` AND v.channel_id NOT IN (` + placeholders(len(search.ExcludeChannelIDs)) + `)`
+			for _, channelID := range search.ExcludeChannelIDs {
+				args = append(args, channelID)
+			}
+		}
+		rows, err := r.DB.Query(r.rebind(query+` LIMIT ?`), append(args, maxTermPostings)...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		hits := make(map[string]searchHit)
+		for rows.Next() {
+			var votingID, channelID string
+			var weight int
+			if err := rows.Scan(&votingID, &weight, &channelID); err != nil {
+				return nil, err
+			}
+			hit := hits[votingID]
+			hits[votingID] = searchHit{ChannelID: channelID, Weight: hit.Weight + weight}
+		}
+		return hits, rows.Err()
+	})
+	if err != nil {
+		r.Logger.Error("Failed to search votings in database", slog.Any("terms", search.Terms))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, "terms", "Failed to search votings in database")
+		return nil, err
+	}
+	votings, err := collectSearchResults(search, candidates, func(votingIDs []string) ([]model.Voting, error) {
+		args := make([]interface{}, len(votingIDs))
+		for i, id := range votingIDs {
+			args[i] = id
+		}
+		return r.queryVotings(`SELECT `+votingColumns+` FROM votings WHERE id IN (`+placeholders(len(votingIDs))+`)`, args...)
+	})
+	if err != nil {
+		r.Logger.Error("Failed to get found votings from database", slog.Any("terms", search.Terms))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, "terms", "Failed to get found votings from database")
+		return nil, err
+	}
+	return votings, nil
+}
+
+// ListPendingVotings returns the votings that change state by themselves:
+// scheduled ones and open ones with a deadline.
+func (r *sqlVotingRepository) ListPendingVotings() ([]model.Voting, error) {
+	votings, err := r.queryVotings(`SELECT `+votingColumns+` FROM votings
+		WHERE state = ? OR (state = ? AND deadline > 0) ORDER BY id`,
+		string(model.StateScheduled), string(model.StateOpen))
+	if err != nil {
+		r.Logger.Error("Failed to list pending votings from database")
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, "state", "Failed to list pending votings from database")
+		return nil, err
+	}
+	return votings, nil
+}
+
+func (r *sqlVotingRepository) SetWeight(entry model.WeightEntry) error {
+	_, err := r.DB.Exec(r.rebind(`INSERT INTO weight_entries (channel_id, table_name, subject, name, weight)
+		VALUES (?, ?, ?, ?, ?) ON CONFLICT (channel_id, table_name, subject, name) DO UPDATE SET weight = excluded.weight`),
+		entry.ChannelID, entry.Table, string(entry.Subject), entry.Name, entry.Weight)
+	if err != nil {
+		r.Logger.Error("can't save weight", slog.String("channel_id", entry.ChannelID), slog.String("table", entry.Table))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, entry.Table, "can't save weight table entry")
+		return err
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) GetWeightTable(channelID, table string) ([]model.WeightEntry, error) {
+	entries, err := r.queryWeights(`SELECT channel_id, table_name, subject, name, weight FROM weight_entries
+		WHERE channel_id = ? AND table_name = ? ORDER BY subject, name`, channelID, table)
+	if err != nil {
+		r.Logger.Error("Failed to get weight table from database", slog.String("channel_id", channelID), slog.String("table", table))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, table, "Failed to get weight table from database")
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *sqlVotingRepository) ListWeightTables(channelID string) ([]string, error) {
+	entries, err := r.queryWeights(`SELECT channel_id, table_name, subject, name, weight FROM weight_entries
+		WHERE channel_id = ? ORDER BY table_name, subject, name`, channelID)
+	if err != nil {
+		r.Logger.Error("Failed to list weight tables from database", slog.String("channel_id", channelID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, channelID, "Failed to list weight tables from database")
+		return nil, err
+	}
+	return weightTableNames(entries), nil
+}
+
+func (r *sqlVotingRepository) queryWeights(query string, args ...interface{}) ([]model.WeightEntry, error) {
+	rows, err := r.DB.Query(r.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.WeightEntry
+	for rows.Next() {
+		var entry model.WeightEntry
+		var subject string
+		if err := rows.Scan(&entry.ChannelID, &entry.Table, &subject, &entry.Name, &entry.Weight); err != nil {
+			return nil, err
+		}
+		entry.Subject = model.WeightSubject(subject)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *sqlVotingRepository) setTerms(tx *sql.Tx, votingID string, terms map[string]int) error {
+	if _, err := tx.Exec(r.rebind(`DELETE FROM voting_terms WHERE voting_id = ?`), votingID); err != nil {
+		return err
+	}
+	for term, weight := range terms {
+		_, err := tx.Exec(r.rebind(`INSERT INTO voting_terms (term, voting_id, weight) VALUES (?, ?, ?)`), term, votingID, weight)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) queryVotings(query string, args ...interface{}) ([]model.Voting, error) {
+	rows, err := r.DB.Query(r.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var votings []model.Voting
+	for rows.Next() {
+		voting, err := scanVoting(rows)
+		if err != nil {
+			return nil, err
+		}
+		votings = append(votings, voting)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Release the connection before loading options: SQLite runs with one.
+	rows.Close()
+
+	for i := range votings {
+		if err := r.loadOptions(&votings[i]); err != nil {
+			return nil, err
+		}
+		if err := r.loadTransitions(&votings[i]); err != nil {
+			return nil, err
+		}
+	}
+	return votings, nil
+}
+
+func (r *sqlVotingRepository) loadOptions(voting *model.Voting) error {
+	rows, err := r.DB.Query(r.rebind(`SELECT position, label, votes FROM voting_options
+		WHERE voting_id = ? ORDER BY position`), voting.ID)
+	if err != nil {
+		r.Logger.Error("Failed to get voting options from database", slog.String("id", voting.ID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, voting.ID, "Failed to get voting options from database")
+		return err
+	}
+	defer rows.Close()
+
+	voting.Options = nil
+	voting.Results = make(map[int]int)
+	for rows.Next() {
+		var position, votes int
+		var label string
+		if err := rows.Scan(&position, &label, &votes); err != nil {
+			err = errors.InvalidFormat.Wrapf(err, errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, voting.ID, "Failed to decode voting options")
+			return err
+		}
+		voting.Options = append(voting.Options, label)
+		voting.Results[position] = votes
+	}
+	if err := rows.Err(); err != nil {
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, voting.ID, "Failed to get voting options from database")
+		return err
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) loadTransitions(voting *model.Voting) error {
+	rows, err := r.DB.Query(r.rebind(`SELECT from_state, to_state, at, members FROM voting_transitions
+		WHERE voting_id = ? ORDER BY seq`), voting.ID)
+	if err != nil {
+		r.Logger.Error("Failed to get voting transitions from database", slog.String("id", voting.ID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, voting.ID, "Failed to get voting transitions from database")
+		return err
+	}
+	defer rows.Close()
+
+	voting.Transitions = nil
+	for rows.Next() {
+		var from, to string
+		var at int64
+		var members int
+		if err := rows.Scan(&from, &to, &at, &members); err != nil {
+			err = errors.InvalidFormat.Wrapf(err, errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, voting.ID, "Failed to decode voting transitions")
+			return err
+		}
+		voting.Transitions = append(voting.Transitions, model.StateTransition{
+			From:    model.VotingState(from),
+			To:      model.VotingState(to),
+			At:      fromUnixTime(at),
+			Members: members,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, voting.ID, "Failed to get voting transitions from database")
+		return err
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) DeleteVoting(votingID string) (string, error) {
+	err := r.inTx(func(tx *sql.Tx) error {
+		for _, table := range []string{"ballot_choices", "ballots", "voting_options", "voting_terms", "voting_transitions"} {
+			if _, err := tx.Exec(r.rebind(`DELETE FROM `+table+` WHERE voting_id = ?`), votingID); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(r.rebind(`DELETE FROM votings WHERE id = ?`), votingID)
+		return err
+	})
+	if err != nil {
+		r.Logger.Error("Failed to delete voting data", slog.String("id", votingID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to delete voting data")
+		return "", err
+	}
+	return votingID, nil
+}
+
+// TransitionVoting moves a voting from transition.From to transition.To and
+// sets its deadline. It fails with errors.InvalidTransition when the voting
+// is no longer in transition.From, so concurrent changes cannot both win.
+func (r *sqlVotingRepository) TransitionVoting(votingID string, transition model.StateTransition, deadline time.Time) (model.Voting, error) {
+	var current string
+	err := r.inTx(func(tx *sql.Tx) error {
+		query := `UPDATE votings SET state = ?, deadline = ?`
+		args := []interface{}{string(transition.To), unixTime(deadline)}
+		if transition.To == model.StateClosed {
+			query += `, closed_at = ?`
+			args = append(args, unixTime(transition.At))
+		}
+		query += ` WHERE id = ? AND state = ?`
+		args = append(args, votingID, string(transition.From))
+
+		res, err := tx.Exec(r.rebind(query), args...)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return tx.QueryRow(r.rebind(`SELECT state FROM votings WHERE id = ?`), votingID).Scan(&current)
+		}
+
+		_, err = tx.Exec(r.rebind(`INSERT INTO voting_transitions (voting_id, seq, from_state, to_state, at, members)
+			SELECT ?, COALESCE(MAX(seq), 0) + 1, ?, ?, ?, ? FROM voting_transitions WHERE voting_id = ?`),
+			votingID, string(transition.From), string(transition.To), unixTime(transition.At), transition.Members, votingID)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return model.Voting{}, votingNotFound(votingID)
+	}
+	if err != nil {
+		r.Logger.Error("Failed to change voting state", slog.String("id", votingID), slog.String("to", string(transition.To)))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to change voting state")
+		return model.Voting{}, err
+	}
+	if current != "" {
+		return model.Voting{}, stateConflict(votingID, model.VotingState(current), transition)
+	}
+	return r.GetVoting(votingID)
+}
+
+func (r *sqlVotingRepository) SetVotingPost(votingID, postID string) error {
+	res, err := r.DB.Exec(r.rebind(`UPDATE votings SET post_id = ? WHERE id = ?`), postID, votingID)
+	if err != nil {
+		r.Logger.Error("Failed to set voting post", slog.String("id", votingID))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to set voting post")
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return votingNotFound(votingID)
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) ReplaceBallot(ballot model.Ballot, counted int) (model.Ballot, bool, error) {
+	var previous model.Ballot
+	replaced := false
+	err := r.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(r.rebind(`INSERT INTO ballots (`+ballotColumns+`)
+			VALUES (?, ?, ?, ?, ?) ON CONFLICT (voting_id, user_id) DO NOTHING`),
+			ballot.VotingID, ballot.UserID, ballot.Choices[0], unixTime(ballot.CastAt), ballot.Weight)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 1 {
+			if err := r.insertChoices(tx, ballot); err != nil {
+				return err
+			}
+			return r.moveResults(tx, ballot.VotingID, resultDeltas(nil, ballot.Choices, counted))
+		}
+
+		query := `SELECT ` + ballotColumns + ` FROM ballots WHERE voting_id = ? AND user_id = ?`
+		if r.driver == DriverPostgres {
+			query += ` FOR UPDATE`
+		}
+		previous, err = scanBallot(tx.QueryRow(r.rebind(query), ballot.VotingID, ballot.UserID))
+		if err != nil {
+			return err
+		}
+		if err := r.loadChoices(tx, []*model.Ballot{&previous}); err != nil {
+			return err
+		}
+		replaced = true
+
+		_, err = tx.Exec(r.rebind(`UPDATE ballots SET option_position = ?, cast_at = ?, weight = ? WHERE voting_id = ? AND user_id = ?`),
+			ballot.Choices[0], unixTime(ballot.CastAt), ballot.Weight, ballot.VotingID, ballot.UserID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(r.rebind(`DELETE FROM ballot_choices WHERE voting_id = ? AND user_id = ?`), ballot.VotingID, ballot.UserID)
+		if err != nil {
+			return err
+		}
+		if err := r.insertChoices(tx, ballot); err != nil {
+			return err
+		}
+		return r.moveResults(tx, ballot.VotingID, resultDeltas(previous.Choices, ballot.Choices, counted))
+	})
+	if err != nil {
+		r.Logger.Error("can't save ballot", slog.String("voting_id", ballot.VotingID), slog.String("user_id", ballot.UserID))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, ballot.VotingID, "can't save ballot for this voting")
+		return model.Ballot{}, false, err
+	}
+	return previous, replaced, nil
+}
+
+func (r *sqlVotingRepository) GetBallot(votingID, userID string) (model.Ballot, error) {
+	ballot, err := scanBallot(r.DB.QueryRow(r.rebind(`SELECT `+ballotColumns+`
+		FROM ballots WHERE voting_id = ? AND user_id = ?`), votingID, userID))
+	if err == nil {
+		err = r.loadChoices(r.DB, []*model.Ballot{&ballot})
+	}
+	if err == sql.ErrNoRows {
+		return model.Ballot{}, ballotNotFound(userID)
+	}
+	if err != nil {
+		r.Logger.Error("Failed to get ballot from database", slog.String("voting_id", votingID), slog.String("user_id", userID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to get ballot from database")
+		return model.Ballot{}, err
+	}
+	return ballot, nil
+}
+
+func (r *sqlVotingRepository) GetBallots(votingID string) ([]model.Ballot, error) {
+	rows, err := r.DB.Query(r.rebind(`SELECT `+ballotColumns+`
+		FROM ballots WHERE voting_id = ? ORDER BY user_id`), votingID)
+	if err != nil {
+		r.Logger.Error("Failed to get ballots from database", slog.String("voting_id", votingID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to get ballots from database")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ballots []model.Ballot
+	for rows.Next() {
+		ballot, err := scanBallot(rows)
+		if err != nil {
+			err = errors.InvalidFormat.Wrapf(err, errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, votingID, "Failed to decode ballot")
+			return nil, err
+		}
+		ballots = append(ballots, ballot)
+	}
+	if err := rows.Err(); err != nil {
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to get ballots from database")
+		return nil, err
+	}
+	// Release the connection before loading choices: SQLite runs with one.
+	rows.Close()
+
+	pointers := make([]*model.Ballot, len(ballots))
+	for i := range ballots {
+		pointers[i] = &ballots[i]
+	}
+	if err := r.loadChoices(r.DB, pointers); err != nil {
+		r.Logger.Error("Failed to get ballot choices from database", slog.String("voting_id", votingID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to get ballot choices from database")
+		return nil, err
+	}
+	return ballots, nil
+}
+
+func (r *sqlVotingRepository) CountBallots(votingIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(votingIDs))
+	if len(votingIDs) == 0 {
+		return counts, nil
+	}
+	args := make([]interface{}, len(votingIDs))
+	for i, id := range votingIDs {
+		args[i] = id
+	}
+	rows, err := r.DB.Query(r.rebind(`SELECT voting_id, COUNT(*) FROM ballots
+		WHERE voting_id IN (`+placeholders(len(votingIDs))+`) GROUP BY voting_id`), args...)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var votingID string
+			var count int
+			if err = rows.Scan(&votingID, &count); err != nil {
+				break
+			}
+			counts[votingID] = count
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+	}
+	if err != nil {
+		r.Logger.Error("Failed to count ballots in database", slog.Int("votings", len(votingIDs)))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, "voting_ids", "Failed to count ballots in database")
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (r *sqlVotingRepository) DeleteBallot(votingID, userID string, counted int) (model.Ballot, error) {
+	var ballot model.Ballot
+	err := r.inTx(func(tx *sql.Tx) error {
+		query := `SELECT ` + ballotColumns + ` FROM ballots WHERE voting_id = ? AND user_id = ?`
+		if r.driver == DriverPostgres {
+			query += ` FOR UPDATE`
+		}
+		var err error
+		ballot, err = scanBallot(tx.QueryRow(r.rebind(query), votingID, userID))
+		if err != nil {
+			return err
+		}
+		// Read the choices first: deleting the ballot cascades to them.
+		if err := r.loadChoices(tx, []*model.Ballot{&ballot}); err != nil {
+			return err
+		}
+		_, err = tx.Exec(r.rebind(`DELETE FROM ballots WHERE voting_id = ? AND user_id = ?`), votingID, userID)
+		if err != nil {
+			return err
+		}
+		return r.moveResults(tx, votingID, resultDeltas(ballot.Choices, nil, counted))
+	})
+	if err == sql.ErrNoRows {
+		return model.Ballot{}, ballotNotFound(userID)
+	}
+	if err != nil {
+		r.Logger.Error("Failed to delete ballot", slog.String("voting_id", votingID), slog.String("user_id", userID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to delete ballot")
+		return model.Ballot{}, err
+	}
+	return ballot, nil
+}
+
+// moveResults applies result counter deltas within a ballot's transaction.
+func (r *sqlVotingRepository) moveResults(tx *sql.Tx, votingID string, deltas map[int]int) error {
+	for option, delta := range deltas {
+		_, err := tx.Exec(r.rebind(`UPDATE voting_options SET votes = votes + ? WHERE voting_id = ? AND position = ?`),
+			delta, votingID, option)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) insertOptions(tx *sql.Tx, voting model.Voting) error {
+	for i, option := range voting.Options {
+		_, err := tx.Exec(r.rebind(`INSERT INTO voting_options (voting_id, position, label, votes) VALUES (?, ?, ?, ?)`),
+			voting.ID, i, option, voting.Results[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) insertTransitions(tx *sql.Tx, voting model.Voting) error {
+	for i, transition := range voting.Transitions {
+		_, err := tx.Exec(r.rebind(`INSERT INTO voting_transitions (voting_id, seq, from_state, to_state, at, members) VALUES (?, ?, ?, ?, ?, ?)`),
+			voting.ID, i+1, string(transition.From), string(transition.To), unixTime(transition.At), transition.Members)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) insertChoices(tx *sql.Tx, ballot model.Ballot) error {
+	for i, choice := range ballot.Choices {
+		var score sql.NullInt64
+		if i < len(ballot.Scores) {
+			score = sql.NullInt64{Int64: int64(ballot.Scores[i]), Valid: true}
+		}
+		_, err := tx.Exec(r.rebind(`INSERT INTO ballot_choices (voting_id, user_id, seq, option_position, score) VALUES (?, ?, ?, ?, ?)`),
+			ballot.VotingID, ballot.UserID, i+1, choice, score)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sqlQueryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// loadChoices fills in the choices and scores of ballots from the same voting.
+func (r *sqlVotingRepository) loadChoices(q sqlQueryer, ballots []*model.Ballot) error {
+	if len(ballots) == 0 {
+		return nil
+	}
+	query := `SELECT user_id, option_position, score FROM ballot_choices WHERE voting_id = ?`
+	args := []interface{}{ballots[0].VotingID}
+	if len(ballots) == 1 {
+		query += ` AND user_id = ?`
+		args = append(args, ballots[0].UserID)
+	}
+	rows, err := q.Query(r.rebind(query+` ORDER BY user_id, seq`), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	choices := make(map[string][]int, len(ballots))
+	scores := make(map[string][]int)
+	for rows.Next() {
+		var userID string
+		var option int
+		var score sql.NullInt64
+		if err := rows.Scan(&userID, &option, &score); err != nil {
+			return err
+		}
+		choices[userID] = append(choices[userID], option)
+		if score.Valid {
+			scores[userID] = append(scores[userID], int(score.Int64))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, ballot := range ballots {
+		if userChoices, ok := choices[ballot.UserID]; ok {
+			ballot.Choices = userChoices
+			ballot.Scores = scores[ballot.UserID]
+		}
+	}
+	return nil
+}
+
+func (r *sqlVotingRepository) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// placeholders lists n "?" placeholders for an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// rebind turns "?" placeholders into the numbered form PostgreSQL expects.
+func (r *sqlVotingRepository) rebind(query string) string {
+	if r.driver != DriverPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanVoting(row rowScanner) (model.Voting, error) {
+	var voting model.Voting
+	var createdAt, closedAt, deadline, opensAt int64
+	var state, settings string
+	err := row.Scan(&voting.ID, &voting.CreatorID, &voting.Question, &voting.ChannelID, &createdAt, &closedAt, &state, &deadline, &opensAt, &settings, &voting.PostID)
+	if err != nil {
+		return model.Voting{}, err
+	}
+	if err := json.Unmarshal([]byte(settings), &voting.Settings); err != nil {
+		return model.Voting{}, err
+	}
+	voting.State = model.VotingState(state)
+	voting.CreatedAt = fromUnixTime(createdAt)
+	voting.ClosedAt = fromUnixTime(closedAt)
+	voting.Deadline = fromUnixTime(deadline)
+	voting.OpensAt = fromUnixTime(opensAt)
+	return voting, nil
+}
+
+func settingsJSON(settings model.VotingSettings) string {
+	data, _ := json.Marshal(settings) // a struct of plain fields always marshals
+	return string(data)
+}
+
+const ballotColumns = `voting_id, user_id, option_position, cast_at, weight`
+
+func scanBallot(row rowScanner) (model.Ballot, error) {
+	var ballot model.Ballot
+	var castAt int64
+	var option int
+	if err := row.Scan(&ballot.VotingID, &ballot.UserID, &option, &castAt, &ballot.Weight); err != nil {
+		return model.Ballot{}, err
+	}
+	ballot.Choices = []int{option}
+	ballot.CastAt = fromUnixTime(castAt)
+	return ballot, nil
+}
+
+// Timestamps are stored as seconds since epoch, the zero time as 0, the same
+// as in the Tarantool tuples.
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func fromUnixTime(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
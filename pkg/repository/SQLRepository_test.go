@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"go-voting-bot/pkg/model"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSQLiteForeignKeysOnEveryConnection(t *testing.T) {
+	repo := openTestSQLite(t)
+	// Close connections as soon as they are released, so that every query
+	// below runs on a new one.
+	repo.DB.SetMaxIdleConns(0)
+
+	for i := 0; i < 3; i++ {
+		var enabled int
+		if err := repo.DB.QueryRow(`PRAGMA foreign_keys`).Scan(&enabled); err != nil {
+			t.Fatalf("read pragma: %v", err)
+		}
+		if enabled != 1 {
+			t.Fatalf("foreign keys are off on connection %d", i)
+		}
+	}
+
+	ballot := model.Ballot{VotingID: uuid.NewString(), UserID: "voter", Choices: []int{0}, Weight: 1, CastAt: time.Now()}
+	if _, _, err := repo.ReplaceBallot(ballot, 1); err == nil {
+		t.Error("saved a ballot of a missing voting")
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	tests := map[string]string{
+		"/data/voting-bot.db":                       "/data/voting-bot.db?_pragma=foreign_keys(1)",
+		"file:voting.db?_pragma=busy_timeout(5000)": "file:voting.db?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)",
+	}
+	for dsn, want := range tests {
+		if got := sqliteDSN(dsn); got != want {
+			t.Errorf("sqliteDSN(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
@@ -0,0 +1,708 @@
+package repository
+
+import (
+	"fmt"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/utils"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+type tarantoolMigration struct {
+	Version int
+	Name    string
+	Script  string
+	// Backfill, when set, runs after Script to convert existing data in Go.
+	Backfill func(t *votingRepository) error
+}
+
+// Migrations are applied in order and must never be edited once released:
+// add a new entry instead. Scripts should be safe to re-run, because a
+// migration is recorded only after its script succeeds.
+var tarantoolMigrations = []tarantoolMigration{
+	{
+		Version: 1,
+		Name:    "create votings space",
+		Script: `
+			box.schema.space.create('votings', { if_not_exists = true })
+			box.space.votings:format({
+				{ name = 'id',         type = 'string' },
+				{ name = 'creator_id', type = 'string' },
+				{ name = 'question',   type = 'string' },
+				{ name = 'channel_id', type = 'string' },
+				{ name = 'options',    type = 'array' },
+				{ name = 'created_at', type = 'number' },
+				{ name = 'closed_at',  type = 'number' },
+				{ name = 'results',    type = 'array' },
+				{ name = 'is_active',  type = 'boolean' },
+			})
+			box.space.votings:create_index('primary', {
+				parts = { 'id' },
+				unique = true,
+				if_not_exists = true,
+			})
+			box.space.votings:create_index('channel_id', {
+				parts = { 'channel_id' },
+				unique = false,
+				if_not_exists = true,
+			})
+		`,
+	},
+	{
+		Version: 2,
+		Name:    "create ballots space",
+		Script: `
+			box.schema.space.create('ballots', { if_not_exists = true })
+			box.space.ballots:format({
+				{ name = 'voting_id', type = 'string' },
+				{ name = 'user_id',   type = 'string' },
+				{ name = 'option',    type = 'unsigned' },
+				{ name = 'cast_at',   type = 'number' },
+			})
+			box.space.ballots:create_index('primary', {
+				parts = { 'voting_id', 'user_id' },
+				unique = true,
+				if_not_exists = true,
+			})
+		`,
+	},
+	{
+		Version: 3,
+		Name:    "create vote counting functions",
+		Script: `
+			local function define(name, body)
+				if box.schema.func.exists(name) then
+					box.schema.func.drop(name)
+				end
+				box.schema.func.create(name, { body = body })
+			end
+
+			define('voting_replace_ballot', [=[
+				function(ballot)
+					return box.atomic(function()
+						local previous = box.space.ballots:get({ ballot[1], ballot[2] })
+						box.space.ballots:replace(ballot)
+						return previous
+					end)
+				end
+			]=])
+
+			define('voting_increment_result', [=[
+				function(voting_id, option, delta)
+					return box.space.votings:update({ voting_id }, {
+						{ '+', 'results[' .. (option + 1) .. ']', delta },
+					})
+				end
+			]=])
+		`,
+	},
+	{
+		Version: 4,
+		Name:    "index votings by channel and status",
+		Script: `
+			box.space.votings.index.channel_id:alter({
+				parts = { 'channel_id', 'created_at' },
+			})
+			box.space.votings:create_index('channel_status', {
+				parts = { 'channel_id', 'is_active', 'created_at' },
+				unique = false,
+				if_not_exists = true,
+			})
+		`,
+	},
+	{
+		Version: 5,
+		Name:    "create search terms space",
+		Script: `
+			box.schema.space.create('voting_terms', { if_not_exists = true })
+			box.space.voting_terms:format({
+				{ name = 'term',      type = 'string' },
+				{ name = 'voting_id', type = 'string' },
+				{ name = 'weight',    type = 'unsigned' },
+			})
+			box.space.voting_terms:create_index('primary', {
+				parts = { 'term', 'voting_id' },
+				unique = true,
+				if_not_exists = true,
+			})
+			box.space.voting_terms:create_index('voting_id', {
+				parts = { 'voting_id' },
+				unique = false,
+				if_not_exists = true,
+			})
+
+			if box.schema.func.exists('voting_set_terms') then
+				box.schema.func.drop('voting_set_terms')
+			end
+			box.schema.func.create('voting_set_terms', { body = [=[
+				function(voting_id, terms)
+					return box.atomic(function()
+						for _, old in ipairs(box.space.voting_terms.index.voting_id:select({ voting_id })) do
+							box.space.voting_terms:delete({ old[1], old[2] })
+						end
+						for _, term in ipairs(terms) do
+							box.space.voting_terms:replace({ term[1], voting_id, term[2] })
+						end
+					end)
+				end
+			]=] })
+		`,
+		Backfill: func(t *votingRepository) error {
+			after := ""
+			for {
+				var votings []model.Voting
+				err := t.Conn.SelectTyped("votings", "primary", 0, 100, tarantool.IterGt, []interface{}{after}, &votings)
+				if err != nil {
+					return err
+				}
+				if len(votings) == 0 {
+					return nil
+				}
+				for _, voting := range votings {
+					if err := t.setTerms(voting.ID, utils.SearchTerms(voting.Question, voting.Options)); err != nil {
+						return err
+					}
+				}
+				after = votings[len(votings)-1].ID
+			}
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add voting deadlines",
+		Script: `
+			for _, voting in box.space.votings:pairs() do
+				if #voting < 10 then
+					box.space.votings:update({ voting[1] }, { { '!', 10, 0 } })
+				end
+			end
+			box.space.votings:format({
+				{ name = 'id',         type = 'string' },
+				{ name = 'creator_id', type = 'string' },
+				{ name = 'question',   type = 'string' },
+				{ name = 'channel_id', type = 'string' },
+				{ name = 'options',    type = 'array' },
+				{ name = 'created_at', type = 'number' },
+				{ name = 'closed_at',  type = 'number' },
+				{ name = 'results',    type = 'array' },
+				{ name = 'is_active',  type = 'boolean' },
+				{ name = 'deadline',   type = 'number' },
+			})
+			box.space.votings:create_index('deadline', {
+				parts = { 'is_active', 'deadline' },
+				unique = false,
+				if_not_exists = true,
+			})
+		`,
+	},
+	{
+		Version: 7,
+		Name:    "replace is_active with lifecycle state",
+		Script: `
+			local votings = box.space.votings
+			for _, name in ipairs({ 'channel_status', 'deadline' }) do
+				if votings.index[name] ~= nil then
+					votings.index[name]:drop()
+				end
+			end
+
+			local format = votings:format()
+			format[9] = { name = 'state', type = 'any' }
+			votings:format(format)
+
+			local legacy = {}
+			for _, voting in votings:pairs() do
+				if type(voting[9]) == 'boolean' then
+					table.insert(legacy, voting)
+				end
+			end
+			for _, voting in ipairs(legacy) do
+				local transitions = { { '', 'open', voting[6] } }
+				if not voting[9] then
+					table.insert(transitions, { 'open', 'closed', voting[7] })
+				end
+				votings:update({ voting[1] }, {
+					{ '=', 9, voting[9] and 'open' or 'closed' },
+					{ '!', 11, 0 },
+					{ '!', 12, transitions },
+				})
+			end
+
+			format[9] = { name = 'state', type = 'string' }
+			format[11] = { name = 'opens_at', type = 'number' }
+			format[12] = { name = 'transitions', type = 'array' }
+			votings:format(format)
+
+			votings:create_index('channel_state', {
+				parts = { 'channel_id', 'state', 'created_at' },
+				unique = false,
+				if_not_exists = true,
+			})
+			votings:create_index('state_deadline', {
+				parts = { 'state', 'deadline' },
+				unique = false,
+				if_not_exists = true,
+			})
+
+			if box.schema.func.exists('voting_transition') then
+				box.schema.func.drop('voting_transition')
+			end
+			box.schema.func.create('voting_transition', { body = [=[
+				function(voting_id, from, to, at, deadline)
+					return box.atomic(function()
+						local voting = box.space.votings:get({ voting_id })
+						if voting == nil or voting[9] ~= from then
+							return false, voting
+						end
+						local transitions = voting[12]
+						table.insert(transitions, { from, to, at })
+						local ops = {
+							{ '=', 9, to },
+							{ '=', 10, deadline },
+							{ '=', 12, transitions },
+						}
+						if to == 'closed' then
+							table.insert(ops, { '=', 7, at })
+						end
+						return true, box.space.votings:update({ voting_id }, ops)
+					end)
+				end
+			]=] })
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "add voting settings and multiple ballot choices",
+		Script: `
+			local votings = box.space.votings
+			local legacy = {}
+			for _, voting in votings:pairs() do
+				if #voting < 13 then
+					table.insert(legacy, voting[1])
+				end
+			end
+			for _, id in ipairs(legacy) do
+				votings:update({ id }, { { '!', 13, setmetatable({}, { __serialize = 'map' }) } })
+			end
+			local format = votings:format()
+			format[13] = { name = 'settings', type = 'map' }
+			votings:format(format)
+
+			local ballots = box.space.ballots
+			legacy = {}
+			for _, ballot in ballots:pairs() do
+				if #ballot < 5 then
+					table.insert(legacy, ballot)
+				end
+			end
+			for _, ballot in ipairs(legacy) do
+				ballots:update({ ballot[1], ballot[2] }, { { '!', 5, { ballot[3] } } })
+			end
+			format = ballots:format()
+			format[5] = { name = 'choices', type = 'array' }
+			ballots:format(format)
+		`,
+	},
+	{
+		Version: 9,
+		Name:    "add ballot scores",
+		Script: `
+			local ballots = box.space.ballots
+			local legacy = {}
+			for _, ballot in ballots:pairs() do
+				if #ballot < 6 then
+					table.insert(legacy, { ballot[1], ballot[2] })
+				end
+			end
+			for _, key in ipairs(legacy) do
+				ballots:update(key, { { '!', 6, {} } })
+			end
+			local format = ballots:format()
+			format[6] = { name = 'scores', type = 'array' }
+			ballots:format(format)
+		`,
+	},
+	{
+		Version: 10,
+		Name:    "add weight tables and ballot weights",
+		Script: `
+			box.schema.space.create('weights', { if_not_exists = true })
+			box.space.weights:format({
+				{ name = 'channel_id', type = 'string' },
+				{ name = 'table',      type = 'string' },
+				{ name = 'subject',    type = 'string' },
+				{ name = 'name',       type = 'string' },
+				{ name = 'weight',     type = 'number' },
+			})
+			box.space.weights:create_index('primary', {
+				parts = { 'channel_id', 'table', 'subject', 'name' },
+				unique = true,
+				if_not_exists = true,
+			})
+
+			local ballots = box.space.ballots
+			local legacy = {}
+			for _, ballot in ballots:pairs() do
+				if #ballot < 7 then
+					table.insert(legacy, { ballot[1], ballot[2] })
+				end
+			end
+			for _, key in ipairs(legacy) do
+				ballots:update(key, { { '!', 7, 1 } })
+			end
+			local format = ballots:format()
+			format[7] = { name = 'weight', type = 'number' }
+			ballots:format(format)
+		`,
+	},
+	{
+		Version: 11,
+		Name:    "add voting card post ids",
+		Script: `
+			local votings = box.space.votings
+			local legacy = {}
+			for _, voting in votings:pairs() do
+				if #voting < 14 then
+					table.insert(legacy, voting[1])
+				end
+			end
+			for _, id in ipairs(legacy) do
+				votings:update({ id }, { { '!', 14, '' } })
+			end
+			local format = votings:format()
+			format[14] = { name = 'post_id', type = 'string' }
+			votings:format(format)
+		`,
+	},
+	{
+		Version: 12,
+		Name:    "index votings by card post",
+		Script: `
+			box.space.votings:create_index('post_id', {
+				parts = { 'post_id' },
+				unique = false,
+				if_not_exists = true,
+			})
+		`,
+	},
+	{
+		Version: 13,
+		Name:    "move result counters with ballots",
+		Script: `
+			local function define(name, body)
+				if box.schema.func.exists(name) then
+					box.schema.func.drop(name)
+				end
+				box.schema.func.create(name, { body = body })
+			end
+
+			-- Both functions change the ballot and the counters in one
+			-- transaction; counted is how many leading choices count.
+			define('voting_replace_ballot', [=[
+				function(ballot, counted)
+					return box.atomic(function()
+						if box.space.votings:get({ ballot[1] }) == nil then
+							error('voting not found: ' .. ballot[1])
+						end
+						local previous = box.space.ballots:get({ ballot[1], ballot[2] })
+						box.space.ballots:replace(ballot)
+
+						local deltas = {}
+						if previous ~= nil then
+							for i = 1, math.min(#previous[5], counted) do
+								local option = previous[5][i]
+								deltas[option] = (deltas[option] or 0) - 1
+							end
+						end
+						for i = 1, math.min(#ballot[5], counted) do
+							local option = ballot[5][i]
+							deltas[option] = (deltas[option] or 0) + 1
+						end
+						local ops = {}
+						for option, delta in pairs(deltas) do
+							if delta ~= 0 then
+								table.insert(ops, { '+', 'results[' .. (option + 1) .. ']', delta })
+							end
+						end
+						if #ops > 0 then
+							box.space.votings:update({ ballot[1] }, ops)
+						end
+						return previous
+					end)
+				end
+			]=])
+
+			-- Counters only move with ballots now.
+			if box.schema.func.exists('voting_increment_result') then
+				box.schema.func.drop('voting_increment_result')
+			end
+
+			define('voting_delete_ballot', [=[
+				function(voting_id, user_id, counted)
+					return box.atomic(function()
+						local previous = box.space.ballots:delete({ voting_id, user_id })
+						if previous == nil then
+							return nil
+						end
+						local ops = {}
+						for i = 1, math.min(#previous[5], counted) do
+							table.insert(ops, { '-', 'results[' .. (previous[5][i] + 1) .. ']', 1 })
+						end
+						if #ops > 0 then
+							box.space.votings:update({ voting_id }, ops)
+						end
+						return previous
+					end)
+				end
+			]=])
+		`,
+	},
+	{
+		Version: 14,
+		Name:    "filter search postings by state and channel",
+		Script: `
+			local function define(name, body)
+				if box.schema.func.exists(name) then
+					box.schema.func.drop(name)
+				end
+				box.schema.func.create(name, { body = body })
+			end
+
+			-- Returns up to limit { voting_id, weight, channel_id } postings
+			-- of terms starting with prefix, of votings in one of states and,
+			-- unless channel_ids is empty, in one of those channels, and not
+			-- in one of exclude_channel_ids.
+			define('voting_search_postings', [=[
+				function(prefix, limit, states, channel_ids, exclude_channel_ids)
+					local wanted_states = {}
+					for _, state in ipairs(states) do
+						wanted_states[state] = true
+					end
+					local wanted_channels = nil
+					if #channel_ids > 0 then
+						wanted_channels = {}
+						for _, channel_id in ipairs(channel_ids) do
+							wanted_channels[channel_id] = true
+						end
+					end
+					local excluded_channels = {}
+					for _, channel_id in ipairs(exclude_channel_ids) do
+						excluded_channels[channel_id] = true
+					end
+
+					local postings = setmetatable({}, { __serialize = 'array' })
+					for _, posting in box.space.voting_terms:pairs({ prefix }, { iterator = 'GE' }) do
+						if #postings >= limit or posting[1]:sub(1, #prefix) ~= prefix then
+							break
+						end
+						local voting = box.space.votings:get({ posting[2] })
+						if voting ~= nil and wanted_states[voting[9]]
+							and (wanted_channels == nil or wanted_channels[voting[4]])
+							and not excluded_channels[voting[4]] then
+							table.insert(postings, { posting[2], posting[3], voting[4] })
+						end
+					end
+					return postings
+				end
+			]=])
+
+			define('voting_get_many', [=[
+				function(voting_ids)
+					local votings = setmetatable({}, { __serialize = 'array' })
+					for _, voting_id in ipairs(voting_ids) do
+						local voting = box.space.votings:get({ voting_id })
+						if voting ~= nil then
+							table.insert(votings, voting)
+						end
+					end
+					return votings
+				end
+			]=])
+		`,
+	},
+	{
+		Version: 15,
+		Name:    "delete votings with their ballots",
+		Script: `
+			if box.schema.func.exists('voting_delete') then
+				box.schema.func.drop('voting_delete')
+			end
+			-- Deletes the voting together with its ballots and search terms.
+			box.schema.func.create('voting_delete', { body = [=[
+				function(voting_id)
+					return box.atomic(function()
+						for _, ballot in ipairs(box.space.ballots:select({ voting_id })) do
+							box.space.ballots:delete({ ballot[1], ballot[2] })
+						end
+						for _, term in ipairs(box.space.voting_terms.index.voting_id:select({ voting_id })) do
+							box.space.voting_terms:delete({ term[1], term[2] })
+						end
+						box.space.votings:delete({ voting_id })
+					end)
+				end
+			]=] })
+
+			-- Ballots of votings deleted before this version were left behind.
+			local orphans = {}
+			for _, ballot in box.space.ballots:pairs() do
+				if box.space.votings:get({ ballot[1] }) == nil then
+					table.insert(orphans, { ballot[1], ballot[2] })
+				end
+			end
+			for _, key in ipairs(orphans) do
+				box.space.ballots:delete(key)
+			end
+		`,
+	},
+	{
+		Version: 16,
+		Name:    "count ballots of many votings",
+		Script: `
+			if box.schema.func.exists('voting_count_ballots') then
+				box.schema.func.drop('voting_count_ballots')
+			end
+			-- Returns a map from voting ID to its ballot count, leaving out
+			-- votings without ballots.
+			box.schema.func.create('voting_count_ballots', { body = [=[
+				function(voting_ids)
+					local counts = setmetatable({}, { __serialize = 'map' })
+					for _, voting_id in ipairs(voting_ids) do
+						local count = box.space.ballots.index.primary:count({ voting_id })
+						if count > 0 then
+							counts[voting_id] = count
+						end
+					end
+					return counts
+				end
+			]=] })
+		`,
+	},
+	{
+		Version: 17,
+		Name:    "record channel members on closing",
+		Script: `
+			if box.schema.func.exists('voting_transition') then
+				box.schema.func.drop('voting_transition')
+			end
+			-- members is what the quorum is counted against once the voting
+			-- is closed; it is 0 on other transitions.
+			box.schema.func.create('voting_transition', { body = [=[
+				function(voting_id, from, to, at, deadline, members)
+					return box.atomic(function()
+						local voting = box.space.votings:get({ voting_id })
+						if voting == nil or voting[9] ~= from then
+							return false, voting
+						end
+						local transitions = voting[12]
+						table.insert(transitions, { from, to, at, members })
+						local ops = {
+							{ '=', 9, to },
+							{ '=', 10, deadline },
+							{ '=', 12, transitions },
+						}
+						if to == 'closed' then
+							table.insert(ops, { '=', 7, at })
+						end
+						return true, box.space.votings:update({ voting_id }, ops)
+					end)
+				end
+			]=] })
+		`,
+	},
+	{
+		Version: 18,
+		Name:    "insert votings with their search terms",
+		Script: `
+			if box.schema.func.exists('voting_insert') then
+				box.schema.func.drop('voting_insert')
+			end
+			-- Inserts the voting and its search terms, or neither of them.
+			box.schema.func.create('voting_insert', { body = [=[
+				function(voting, terms)
+					return box.atomic(function()
+						box.space.votings:insert(voting)
+						for _, term in ipairs(terms) do
+							box.space.voting_terms:replace({ term[1], voting[1], term[2] })
+						end
+					end)
+				end
+			]=] })
+		`,
+	},
+}
+
+const createMigrationsSpace = `
+	box.schema.space.create('_migrations', { if_not_exists = true })
+	box.space._migrations:format({
+		{ name = 'version',    type = 'unsigned' },
+		{ name = 'name',       type = 'string' },
+		{ name = 'applied_at', type = 'number' },
+	})
+	box.space._migrations:create_index('primary', {
+		parts = { 'version' },
+		unique = true,
+		if_not_exists = true,
+	})
+`
+
+type appliedMigration struct {
+	_msgpack  struct{} `msgpack:",asArray"`
+	Version   int
+	Name      string
+	AppliedAt int64
+}
+
+// Migrate brings the Tarantool schema up to the latest version known to this
+// binary. It refuses to run against a schema that is newer than that.
+func (t *votingRepository) Migrate() error {
+	if _, err := t.Conn.Eval(createMigrationsSpace, []interface{}{}); err != nil {
+		return fmt.Errorf("failed to create migrations space: %w", err)
+	}
+
+	var applied []appliedMigration
+	err := t.Conn.SelectTyped("_migrations", "primary", 0, math.MaxUint32, tarantool.IterAll, []interface{}{}, &applied)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	current := 0
+	for _, migration := range applied {
+		current = max(current, migration.Version)
+	}
+
+	latest := tarantoolMigrations[len(tarantoolMigrations)-1].Version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, latest)
+	}
+
+	for _, migration := range tarantoolMigrations {
+		if migration.Version <= current {
+			continue
+		}
+
+		t.Logger.Info("Applying migration", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+		if _, err := t.Conn.Eval(migration.Script, []interface{}{}); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		if migration.Backfill != nil {
+			if err := migration.Backfill(t); err != nil {
+				return fmt.Errorf("migration %d (%s) backfill failed: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		record := appliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().Unix(),
+		}
+		if _, err := t.Conn.Insert("_migrations", record); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+	}
+
+	t.Logger.Info("Database schema is up to date", slog.Int("version", latest))
+	return nil
+}
@@ -4,20 +4,41 @@ import (
 	"fmt"
 	"go-voting-bot/pkg/model"
 	"go-voting-bot/pkg/utils"
+	"math"
+	"strings"
 	"time"
 
 	"context"
 	"go-voting-bot/pkg/errors"
 	"log/slog"
 
-	"github.com/mitchellh/mapstructure"
 	"github.com/tarantool/go-tarantool/v2"
 )
 
 type VotingRepository interface {
 	SaveVoting(voting model.Voting) (model.Voting, error)
 	GetVoting(votingID string) (model.Voting, error)
+	GetVotingByPost(postID string) (model.Voting, error)
 	DeleteVoting(votingID string) (string, error)
+	TransitionVoting(votingID string, transition model.StateTransition, deadline time.Time) (model.Voting, error)
+	SetVotingPost(votingID, postID string) error
+	// ReplaceBallot and DeleteBallot move the result counters together with
+	// the ballot, atomically; counted is how many leading choices of a ballot
+	// count, see model.VotingSettings.CountedChoices.
+	ReplaceBallot(ballot model.Ballot, counted int) (model.Ballot, bool, error)
+	GetBallot(votingID, userID string) (model.Ballot, error)
+	GetBallots(votingID string) ([]model.Ballot, error)
+	// CountBallots counts the ballots of each of the votings; votings
+	// without ballots are left out.
+	CountBallots(votingIDs []string) (map[string]int, error)
+	DeleteBallot(votingID, userID string, counted int) (model.Ballot, error)
+	ListVotingsByChannel(channelID string, state model.VotingState, offset, limit int) ([]model.Voting, error)
+	FindVotingByPrefix(prefix string) (model.Voting, error)
+	SearchVotings(search VotingSearch) ([]model.Voting, error)
+	ListPendingVotings() ([]model.Voting, error)
+	SetWeight(entry model.WeightEntry) error
+	GetWeightTable(channelID, table string) ([]model.WeightEntry, error)
+	ListWeightTables(channelID string) ([]string, error)
 }
 
 type votingRepository struct {
@@ -25,13 +46,13 @@ type votingRepository struct {
 	Logger *slog.Logger
 }
 
-func NewTarantoolClient(host string, port string, user string, password string) (*votingRepository, error) {
+func NewTarantoolClient(host string, port string, user string, password string, logger *slog.Logger) (*votingRepository, error) {
 	addr := fmt.Sprintf("%s:%s", host, port)
 
 	dialer := tarantool.NetDialer{
 		Address:  addr,
-		User:     "guest",
-		Password: "",
+		User:     user,
+		Password: password,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -42,7 +63,7 @@ func NewTarantoolClient(host string, port string, user string, password string)
 		return nil, fmt.Errorf("failed to connect to Tarantool: %w", err)
 	}
 
-	return &votingRepository{Conn: conn}, nil
+	return &votingRepository{Conn: conn, Logger: logger}, nil
 }
 
 func (t *votingRepository) Close() error {
@@ -50,17 +71,8 @@ func (t *votingRepository) Close() error {
 }
 
 func (t *votingRepository) SaveVoting(voting model.Voting) (model.Voting, error) {
-	_, err := t.Conn.Insert("votings", []interface{}{
-		voting.ID,
-		voting.CreatorID,
-		voting.ChannelID,
-		voting.Question,
-		voting.Options,
-		voting.CreatedAt,
-		voting.ClosedAt,
-		voting.IsActive,
-		utils.ConvertResultsToMapStringInterface(voting.Results),
-	})
+	terms := termTuples(utils.SearchTerms(voting.Question, voting.Options))
+	_, err := t.Conn.Call17("voting_insert", []interface{}{voting, terms})
 	if err != nil {
 		t.Logger.Error("can't save record with this id", slog.String("id", voting.ID))
 		err = errors.Wrapf(err, errors.NotSaved.Message())
@@ -70,58 +82,94 @@ func (t *votingRepository) SaveVoting(voting model.Voting) (model.Voting, error)
 	return voting, nil
 }
 
-func (t *votingRepository) GetVoting(votingID string) (model.Voting, error) {
-	var result []struct {
-		ID        string
-		CreatorID string
-		ChannelID string
-		Question  string
-		Options   []string
-		CreatedAt time.Time
-		ClosedAt  time.Time
-		IsActive  bool
-		Results   map[string]interface{}
+type transitionResult struct {
+	_msgpack struct{} `msgpack:",asArray"`
+	Applied  bool
+	Voting   *model.Voting
+}
+
+// TransitionVoting moves a voting from transition.From to transition.To and
+// sets its deadline. It fails with errors.InvalidTransition when the voting
+// is no longer in transition.From, so concurrent changes cannot both win.
+func (t *votingRepository) TransitionVoting(votingID string, transition model.StateTransition, deadline time.Time) (model.Voting, error) {
+	var result transitionResult
+	err := t.Conn.Call17Typed("voting_transition", []interface{}{
+		votingID, string(transition.From), string(transition.To), transition.At.Unix(), unixTime(deadline), transition.Members,
+	}, &result)
+	if err != nil {
+		t.Logger.Error("Failed to change voting state", slog.String("id", votingID), slog.String("to", string(transition.To)))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to change voting state")
+		return model.Voting{}, err
+	}
+
+	if result.Voting == nil {
+		return model.Voting{}, votingNotFound(votingID)
+	}
+	if !result.Applied {
+		return model.Voting{}, stateConflict(votingID, result.Voting.State, transition)
+	}
+	return *result.Voting, nil
+}
+
+func (t *votingRepository) SetVotingPost(votingID, postID string) error {
+	// Update operations count fields from zero.
+	ops := tarantool.NewOperations().Assign(model.VotingPostIDField-1, postID)
+	resp, err := t.Conn.Update("votings", "primary", []interface{}{votingID}, ops)
+	if err != nil {
+		t.Logger.Error("Failed to set voting post", slog.String("id", votingID))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to set voting post")
+		return err
+	}
+	if len(resp) == 0 {
+		return votingNotFound(votingID)
 	}
+	return nil
+}
 
-	resp, err := t.Conn.Select("votings", "primary", 0, 1, tarantool.IterEq, []interface{}{votingID})
+func (t *votingRepository) GetVoting(votingID string) (model.Voting, error) {
+	var votings []model.Voting
+	err := t.Conn.SelectTyped("votings", "primary", 0, 1, tarantool.IterEq, []interface{}{votingID}, &votings)
 	if err != nil {
 		t.Logger.Error("Failed to get voting from Tarantool", slog.String("id", votingID))
-		err = errors.Wrapf(err, errors.NotFound.Message())
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
 		err = errors.AddErrorContext(err, votingID, "Failed to get voting from Tarantool")
 		return model.Voting{}, err
 	}
 
-	if len(resp) == 0 {
+	if len(votings) == 0 {
 		t.Logger.Error("Voting not found for ID", slog.String("id", votingID))
-		err = errors.Wrapf(err, errors.WrongType.Message())
+		err = errors.NotFound.New(errors.NotFound.Message())
 		err = errors.AddErrorContext(err, votingID, "Voting not found for ID")
 		return model.Voting{}, err
 	}
 
-	if err := mapstructure.Decode(resp[0], &result); err != nil {
-		t.Logger.Error("Failed to decode voting data", slog.String("id", votingID))
-		err = errors.Wrapf(err, errors.InvalidFormat.Message())
-		err = errors.AddErrorContext(err, votingID, "Failed to decode voting data")
+	return votings[0], nil
+}
+
+// GetVotingByPost finds the voting whose card is the post. Most posts have
+// no voting, so a miss is not logged.
+func (t *votingRepository) GetVotingByPost(postID string) (model.Voting, error) {
+	if postID == "" {
+		return model.Voting{}, votingNotFound(postID)
+	}
+	var votings []model.Voting
+	err := t.Conn.SelectTyped("votings", "post_id", 0, 1, tarantool.IterEq, []interface{}{postID}, &votings)
+	if err != nil {
+		t.Logger.Error("Failed to get voting from Tarantool", slog.String("post_id", postID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, postID, "Failed to get voting from Tarantool")
 		return model.Voting{}, err
 	}
-
-	voting := model.Voting{
-		ID:        result[0].ID,
-		CreatorID: result[0].CreatorID,
-		ChannelID: result[0].ChannelID,
-		Question:  result[0].Question,
-		Options:   result[0].Options,
-		CreatedAt: result[0].CreatedAt,
-		ClosedAt:  result[0].ClosedAt,
-		IsActive:  result[0].IsActive,
-		Results:   utils.ConvertMapStringInterfaceToResults(result[0].Results),
+	if len(votings) == 0 {
+		return model.Voting{}, votingNotFound(postID)
 	}
-
-	return voting, nil
+	return votings[0], nil
 }
 
 func (t *votingRepository) DeleteVoting(votingID string) (string, error) {
-	_, err := t.Conn.Delete("votings", "primary", []interface{}{votingID})
+	_, err := t.Conn.Call17("voting_delete", []interface{}{votingID})
 	if err != nil {
 		t.Logger.Error("Failed to delete voting data", slog.String("id", votingID))
 		err = errors.Wrapf(err, errors.NotFound.Message())
@@ -130,3 +178,271 @@ func (t *votingRepository) DeleteVoting(votingID string) (string, error) {
 	}
 	return votingID, nil
 }
+
+func (t *votingRepository) ReplaceBallot(ballot model.Ballot, counted int) (model.Ballot, bool, error) {
+	var previous []*model.Ballot
+	err := t.Conn.Call17Typed("voting_replace_ballot", []interface{}{ballot, counted}, &previous)
+	if err != nil {
+		t.Logger.Error("can't save ballot", slog.String("voting_id", ballot.VotingID), slog.String("user_id", ballot.UserID))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, ballot.VotingID, "can't save ballot for this voting")
+		return model.Ballot{}, false, err
+	}
+
+	if len(previous) == 0 || previous[0] == nil {
+		return model.Ballot{}, false, nil
+	}
+	return *previous[0], true, nil
+}
+
+func (t *votingRepository) GetBallot(votingID, userID string) (model.Ballot, error) {
+	var ballots []model.Ballot
+	err := t.Conn.SelectTyped("ballots", "primary", 0, 1, tarantool.IterEq, []interface{}{votingID, userID}, &ballots)
+	if err != nil {
+		t.Logger.Error("Failed to get ballot from Tarantool", slog.String("voting_id", votingID), slog.String("user_id", userID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to get ballot from Tarantool")
+		return model.Ballot{}, err
+	}
+
+	if len(ballots) == 0 {
+		err = errors.NotFound.New(errors.NotFound.Message())
+		err = errors.AddErrorContext(err, userID, "Ballot not found for user")
+		return model.Ballot{}, err
+	}
+
+	return ballots[0], nil
+}
+
+func (t *votingRepository) GetBallots(votingID string) ([]model.Ballot, error) {
+	var ballots []model.Ballot
+	err := t.Conn.SelectTyped("ballots", "primary", 0, math.MaxUint32, tarantool.IterEq, []interface{}{votingID}, &ballots)
+	if err != nil {
+		t.Logger.Error("Failed to get ballots from Tarantool", slog.String("voting_id", votingID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to get ballots from Tarantool")
+		return nil, err
+	}
+
+	return ballots, nil
+}
+
+func (t *votingRepository) CountBallots(votingIDs []string) (map[string]int, error) {
+	if len(votingIDs) == 0 {
+		return map[string]int{}, nil
+	}
+	var counts []map[string]int
+	err := t.Conn.Call17Typed("voting_count_ballots", []interface{}{votingIDs}, &counts)
+	if err != nil {
+		t.Logger.Error("Failed to count ballots in Tarantool", slog.Int("votings", len(votingIDs)))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, "voting_ids", "Failed to count ballots in Tarantool")
+		return nil, err
+	}
+	if len(counts) == 0 || counts[0] == nil {
+		return map[string]int{}, nil
+	}
+	return counts[0], nil
+}
+
+func (t *votingRepository) DeleteBallot(votingID, userID string, counted int) (model.Ballot, error) {
+	var ballots []*model.Ballot
+	err := t.Conn.Call17Typed("voting_delete_ballot", []interface{}{votingID, userID, counted}, &ballots)
+	if err != nil {
+		t.Logger.Error("Failed to delete ballot", slog.String("voting_id", votingID), slog.String("user_id", userID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, votingID, "Failed to delete ballot")
+		return model.Ballot{}, err
+	}
+
+	if len(ballots) == 0 || ballots[0] == nil {
+		err = errors.NotFound.New(errors.NotFound.Message())
+		err = errors.AddErrorContext(err, userID, "Ballot not found for user")
+		return model.Ballot{}, err
+	}
+
+	return *ballots[0], nil
+}
+
+// ListVotingsByChannel returns votings of a channel in the given state, or in
+// any state when it is empty, newest first.
+func (t *votingRepository) ListVotingsByChannel(channelID string, state model.VotingState, offset, limit int) ([]model.Voting, error) {
+	index, key := "channel_id", []interface{}{channelID}
+	if state != "" {
+		index, key = "channel_state", []interface{}{channelID, string(state)}
+	}
+
+	var votings []model.Voting
+	err := t.Conn.SelectTyped("votings", index, uint32(offset), uint32(limit), tarantool.IterReq, key, &votings)
+	if err != nil {
+		t.Logger.Error("Failed to list votings from Tarantool", slog.String("channel_id", channelID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, channelID, "Failed to list votings from Tarantool")
+		return nil, err
+	}
+	return votings, nil
+}
+
+// FindVotingByPrefix returns the only voting whose ID starts with prefix.
+func (t *votingRepository) FindVotingByPrefix(prefix string) (model.Voting, error) {
+	var votings []model.Voting
+	err := t.Conn.SelectTyped("votings", "primary", 0, 2, tarantool.IterGe, []interface{}{prefix}, &votings)
+	if err != nil {
+		t.Logger.Error("Failed to get voting from Tarantool", slog.String("prefix", prefix))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, prefix, "Failed to get voting from Tarantool")
+		return model.Voting{}, err
+	}
+	return singleVotingWithPrefix(votings, prefix)
+}
+
+// ListPendingVotings returns the votings that change state by themselves:
+// scheduled ones and open ones with a deadline.
+func (t *votingRepository) ListPendingVotings() ([]model.Voting, error) {
+	var scheduled, open []model.Voting
+	err := t.Conn.SelectTyped("votings", "state_deadline", 0, math.MaxUint32, tarantool.IterEq,
+		[]interface{}{string(model.StateScheduled)}, &scheduled)
+	if err == nil {
+		err = t.Conn.SelectTyped("votings", "state_deadline", 0, math.MaxUint32, tarantool.IterGt,
+			[]interface{}{string(model.StateOpen), 0}, &open)
+	}
+	if err != nil {
+		t.Logger.Error("Failed to list pending votings from Tarantool")
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, "state", "Failed to list pending votings from Tarantool")
+		return nil, err
+	}
+
+	for _, voting := range open {
+		if voting.State != model.StateOpen {
+			break // the iterator went on to the next state
+		}
+		scheduled = append(scheduled, voting)
+	}
+	return scheduled, nil
+}
+
+func (t *votingRepository) SetWeight(entry model.WeightEntry) error {
+	if _, err := t.Conn.Replace("weights", entry); err != nil {
+		t.Logger.Error("can't save weight", slog.String("channel_id", entry.ChannelID), slog.String("table", entry.Table))
+		err = errors.NotSaved.Wrapf(err, errors.NotSaved.Message())
+		err = errors.AddErrorContext(err, entry.Table, "can't save weight table entry")
+		return err
+	}
+	return nil
+}
+
+func (t *votingRepository) GetWeightTable(channelID, table string) ([]model.WeightEntry, error) {
+	var entries []model.WeightEntry
+	err := t.Conn.SelectTyped("weights", "primary", 0, math.MaxUint32, tarantool.IterEq, []interface{}{channelID, table}, &entries)
+	if err != nil {
+		t.Logger.Error("Failed to get weight table from Tarantool", slog.String("channel_id", channelID), slog.String("table", table))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, table, "Failed to get weight table from Tarantool")
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (t *votingRepository) ListWeightTables(channelID string) ([]string, error) {
+	var entries []model.WeightEntry
+	err := t.Conn.SelectTyped("weights", "primary", 0, math.MaxUint32, tarantool.IterEq, []interface{}{channelID}, &entries)
+	if err != nil {
+		t.Logger.Error("Failed to list weight tables from Tarantool", slog.String("channel_id", channelID))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, channelID, "Failed to list weight tables from Tarantool")
+		return nil, err
+	}
+	return weightTableNames(entries), nil
+}
+
+// weightTableNames lists the tables of entries sorted by table.
+func weightTableNames(entries []model.WeightEntry) []string {
+	var tables []string
+	for _, entry := range entries {
+		if len(tables) == 0 || tables[len(tables)-1] != entry.Table {
+			tables = append(tables, entry.Table)
+		}
+	}
+	return tables
+}
+
+type searchPosting struct {
+	_msgpack  struct{} `msgpack:",asArray"`
+	VotingID  string
+	Weight    int
+	ChannelID string
+}
+
+func (t *votingRepository) SearchVotings(search VotingSearch) ([]model.Voting, error) {
+	candidates, err := rankSearchCandidates(search, func(prefix string) (map[string]searchHit, error) {
+		// Lua expects tables, not nil.
+		channelIDs, excludeChannelIDs := []string{}, []string{}
+		channelIDs = append(channelIDs, search.ChannelIDs...)
+		excludeChannelIDs = append(excludeChannelIDs, search.ExcludeChannelIDs...)
+		var postings [][]searchPosting
+		err := t.Conn.Call17Typed("voting_search_postings", []interface{}{
+			prefix, maxTermPostings, search.states(), channelIDs, excludeChannelIDs,
+		}, &postings)
+		if err != nil || len(postings) == 0 {
+			return nil, err
+		}
+		hits := make(map[string]searchHit)
+		for _, posting := range postings[0] {
+			hit := hits[posting.VotingID]
+			hits[posting.VotingID] = searchHit{ChannelID: posting.ChannelID, Weight: hit.Weight + posting.Weight}
+		}
+		return hits, nil
+	})
+	if err != nil {
+		t.Logger.Error("Failed to search votings in Tarantool", slog.Any("terms", search.Terms))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, "terms", "Failed to search votings in Tarantool")
+		return nil, err
+	}
+	votings, err := collectSearchResults(search, candidates, func(votingIDs []string) ([]model.Voting, error) {
+		var votings [][]model.Voting
+		err := t.Conn.Call17Typed("voting_get_many", []interface{}{votingIDs}, &votings)
+		if err != nil || len(votings) == 0 {
+			return nil, err
+		}
+		return votings[0], nil
+	})
+	if err != nil {
+		t.Logger.Error("Failed to get found votings from Tarantool", slog.Any("terms", search.Terms))
+		err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+		err = errors.AddErrorContext(err, "terms", "Failed to get found votings from Tarantool")
+		return nil, err
+	}
+	return votings, nil
+}
+
+func (t *votingRepository) setTerms(votingID string, terms map[string]int) error {
+	_, err := t.Conn.Call17("voting_set_terms", []interface{}{votingID, termTuples(terms)})
+	return err
+}
+
+// termTuples turns search terms into the { term, weight } pairs the Lua
+// functions take.
+func termTuples(terms map[string]int) []interface{} {
+	tuples := make([]interface{}, 0, len(terms))
+	for term, weight := range terms {
+		tuples = append(tuples, []interface{}{term, weight})
+	}
+	return tuples
+}
+
+func singleVotingWithPrefix(votings []model.Voting, prefix string) (model.Voting, error) {
+	var matches []model.Voting
+	for _, voting := range votings {
+		if strings.HasPrefix(voting.ID, prefix) {
+			matches = append(matches, voting)
+		}
+	}
+	if len(matches) != 1 {
+		err := errors.NotFound.New(errors.NotFound.Message())
+		err = errors.AddErrorContext(err, prefix, "No single voting found for ID prefix")
+		return model.Voting{}, err
+	}
+	return matches[0], nil
+}
@@ -0,0 +1,545 @@
+package repository
+
+import (
+	"fmt"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// repositoryBackend opens a fresh repository for one test.
+type repositoryBackend struct {
+	name string
+	open func(t *testing.T) VotingRepository
+}
+
+// testBackends are the storages every repository test runs against.
+// Tarantool runs only when TEST_TARANTOOL_HOST points at a disposable
+// instance; votings get random IDs so tests don't see each other's data.
+func testBackends() []repositoryBackend {
+	return []repositoryBackend{
+		{name: "memory", open: func(t *testing.T) VotingRepository { return NewMemoryRepository() }},
+		{name: "sqlite", open: func(t *testing.T) VotingRepository { return openTestSQLite(t) }},
+		{name: "tarantool", open: openTestTarantool},
+	}
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func openTestSQLite(t *testing.T) *sqlVotingRepository {
+	repo, err := NewSQLRepository(DriverSQLite, filepath.Join(t.TempDir(), "voting.db"), testLogger())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return repo
+}
+
+func openTestTarantool(t *testing.T) VotingRepository {
+	host := os.Getenv("TEST_TARANTOOL_HOST")
+	if host == "" {
+		t.Skip("TEST_TARANTOOL_HOST is not set")
+	}
+	repo, err := NewTarantoolClient(host, os.Getenv("TEST_TARANTOOL_PORT"),
+		os.Getenv("TEST_TARANTOOL_USER"), os.Getenv("TEST_TARANTOOL_PASSWORD"), testLogger())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return repo
+}
+
+// forEachBackend runs test against every backend as a subtest.
+func forEachBackend(t *testing.T, test func(t *testing.T, repo VotingRepository)) {
+	for _, backend := range testBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+}
+
+func newTestVoting(channelID string, options ...string) model.Voting {
+	results := make(map[int]int, len(options))
+	for i := range options {
+		results[i] = 0
+	}
+	return model.Voting{
+		ID:        uuid.NewString(),
+		CreatorID: "creator",
+		Question:  "Куда идём обедать?",
+		ChannelID: channelID,
+		Options:   options,
+		CreatedAt: time.Unix(1700000000, 0),
+		Results:   results,
+		State:     model.StateOpen,
+	}
+}
+
+func mustSave(t *testing.T, repo VotingRepository, voting model.Voting) model.Voting {
+	t.Helper()
+	saved, err := repo.SaveVoting(voting)
+	if err != nil {
+		t.Fatalf("save voting: %v", err)
+	}
+	return saved
+}
+
+func mustGet(t *testing.T, repo VotingRepository, votingID string) model.Voting {
+	t.Helper()
+	voting, err := repo.GetVoting(votingID)
+	if err != nil {
+		t.Fatalf("get voting: %v", err)
+	}
+	return voting
+}
+
+func assertResults(t *testing.T, repo VotingRepository, votingID string, want map[int]int) {
+	t.Helper()
+	if got := mustGet(t, repo, votingID).Results; !reflect.DeepEqual(got, want) {
+		t.Errorf("results are %v, want %v", got, want)
+	}
+}
+
+func assertErrorType(t *testing.T, err error, want errors.ErrorType) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("got no error, want %v", want)
+	}
+	if got := errors.GetType(err); got != want {
+		t.Errorf("got error %v of type %v, want %v", err, got, want)
+	}
+}
+
+func TestSaveAndGetVoting(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		voting := newTestVoting("channel", "Пицца", "Суши")
+		voting.Deadline = time.Unix(1700003600, 0)
+		voting.Settings = model.VotingSettings{Kind: model.KindRanked, Results: model.ResultsVoted}
+		mustSave(t, repo, voting)
+
+		got := mustGet(t, repo, voting.ID)
+		if got.Question != voting.Question || !reflect.DeepEqual(got.Options, voting.Options) ||
+			got.State != voting.State || got.Settings != voting.Settings {
+			t.Errorf("got %+v, want %+v", got, voting)
+		}
+		if !got.CreatedAt.Equal(voting.CreatedAt) || !got.Deadline.Equal(voting.Deadline) {
+			t.Errorf("got times %v and %v, want %v and %v", got.CreatedAt, got.Deadline, voting.CreatedAt, voting.Deadline)
+		}
+
+		if _, err := repo.SaveVoting(voting); err == nil {
+			t.Error("saved a voting twice under one ID")
+		}
+		_, err := repo.GetVoting(uuid.NewString())
+		assertErrorType(t, err, errors.NotFound)
+	})
+}
+
+func TestVotingPost(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		voting := mustSave(t, repo, newTestVoting("channel", "Да", "Нет"))
+		postID := uuid.NewString()
+		if err := repo.SetVotingPost(voting.ID, postID); err != nil {
+			t.Fatalf("set post: %v", err)
+		}
+
+		got, err := repo.GetVotingByPost(postID)
+		if err != nil {
+			t.Fatalf("get by post: %v", err)
+		}
+		if got.ID != voting.ID || got.PostID != postID {
+			t.Errorf("got voting %s with post %s, want %s with %s", got.ID, got.PostID, voting.ID, postID)
+		}
+
+		_, err = repo.GetVotingByPost(uuid.NewString())
+		assertErrorType(t, err, errors.NotFound)
+		_, err = repo.GetVotingByPost("")
+		assertErrorType(t, err, errors.NotFound)
+		assertErrorType(t, repo.SetVotingPost(uuid.NewString(), postID), errors.NotFound)
+	})
+}
+
+func TestDeleteVoting(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		channelID := uuid.NewString()
+		deleted := mustSave(t, repo, newTestVoting(channelID, "Да", "Нет"))
+		kept := mustSave(t, repo, newTestVoting(channelID, "Да", "Нет"))
+		for _, voting := range []model.Voting{deleted, kept} {
+			ballot := model.Ballot{VotingID: voting.ID, UserID: "user", Choices: []int{0}, Weight: 1, CastAt: time.Unix(1700000000, 0)}
+			if _, _, err := repo.ReplaceBallot(ballot, 1); err != nil {
+				t.Fatalf("cast ballot: %v", err)
+			}
+		}
+
+		if _, err := repo.DeleteVoting(deleted.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		_, err := repo.GetVoting(deleted.ID)
+		assertErrorType(t, err, errors.NotFound)
+		if ballots, err := repo.GetBallots(deleted.ID); err != nil || len(ballots) != 0 {
+			t.Errorf("got ballots %+v, %v of a deleted voting, want none", ballots, err)
+		}
+		if ballots, err := repo.GetBallots(kept.ID); err != nil || len(ballots) != 1 {
+			t.Errorf("got ballots %+v, %v of the other voting, want one", ballots, err)
+		}
+		found, err := repo.SearchVotings(VotingSearch{Terms: []string{"обедать"}, ChannelIDs: []string{channelID}, Limit: 10})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(found) != 1 || found[0].ID != kept.ID {
+			t.Errorf("search found %d votings, want only the voting left", len(found))
+		}
+	})
+}
+
+func TestCountBallots(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		busy := mustSave(t, repo, newTestVoting("channel", "Да", "Нет"))
+		quiet := mustSave(t, repo, newTestVoting("channel", "Да", "Нет"))
+		for _, userID := range []string{"first", "second"} {
+			ballot := model.Ballot{VotingID: busy.ID, UserID: userID, Choices: []int{1}, Weight: 1, CastAt: time.Unix(1700000000, 0)}
+			if _, _, err := repo.ReplaceBallot(ballot, 1); err != nil {
+				t.Fatalf("cast ballot: %v", err)
+			}
+		}
+
+		counts, err := repo.CountBallots([]string{busy.ID, quiet.ID, uuid.NewString()})
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		if want := map[string]int{busy.ID: 2}; !reflect.DeepEqual(counts, want) {
+			t.Errorf("got counts %v, want %v", counts, want)
+		}
+		if counts, err := repo.CountBallots(nil); err != nil || len(counts) != 0 {
+			t.Errorf("got counts %v, %v of no votings, want none", counts, err)
+		}
+	})
+}
+
+func TestBallotsMoveResults(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		counted int
+		ballots [][]int
+		want    map[int]int
+	}{
+		{name: "single choice", counted: 1 << 30, ballots: [][]int{{0}}, want: map[int]int{0: 1, 1: 0, 2: 0}},
+		{name: "moved vote", counted: 1 << 30, ballots: [][]int{{0}, {2}}, want: map[int]int{0: 0, 1: 0, 2: 1}},
+		{name: "multiple choice", counted: 1 << 30, ballots: [][]int{{0, 1}, {1, 2}}, want: map[int]int{0: 0, 1: 1, 2: 1}},
+		{name: "ranking", counted: 1, ballots: [][]int{{2, 0, 1}, {1, 2}}, want: map[int]int{0: 0, 1: 1, 2: 0}},
+		{name: "scores", counted: 0, ballots: [][]int{{0, 1, 2}}, want: map[int]int{0: 0, 1: 0, 2: 0}},
+	}
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				voting := mustSave(t, repo, newTestVoting("channel", "A", "B", "C"))
+				var last model.Ballot
+				for i, choices := range tt.ballots {
+					last = model.Ballot{VotingID: voting.ID, UserID: "voter", Choices: choices, Weight: 1, CastAt: at}
+					previous, replaced, err := repo.ReplaceBallot(last, tt.counted)
+					if err != nil {
+						t.Fatalf("replace ballot: %v", err)
+					}
+					if replaced != (i > 0) {
+						t.Errorf("ballot %d replaced %v", i, replaced)
+					}
+					if i > 0 && !reflect.DeepEqual(previous.Choices, tt.ballots[i-1]) {
+						t.Errorf("ballot %d replaced choices %v, want %v", i, previous.Choices, tt.ballots[i-1])
+					}
+				}
+				assertResults(t, repo, voting.ID, tt.want)
+
+				stored, err := repo.GetBallot(voting.ID, "voter")
+				if err != nil {
+					t.Fatalf("get ballot: %v", err)
+				}
+				if !reflect.DeepEqual(stored.Choices, last.Choices) || !stored.CastAt.Equal(at) {
+					t.Errorf("stored ballot %+v, want %+v", stored, last)
+				}
+
+				deleted, err := repo.DeleteBallot(voting.ID, "voter", tt.counted)
+				if err != nil {
+					t.Fatalf("delete ballot: %v", err)
+				}
+				if !reflect.DeepEqual(deleted.Choices, last.Choices) {
+					t.Errorf("deleted choices %v, want %v", deleted.Choices, last.Choices)
+				}
+				assertResults(t, repo, voting.ID, map[int]int{0: 0, 1: 0, 2: 0})
+
+				_, err = repo.GetBallot(voting.ID, "voter")
+				assertErrorType(t, err, errors.NotFound)
+				_, err = repo.DeleteBallot(voting.ID, "voter", tt.counted)
+				assertErrorType(t, err, errors.NotFound)
+			})
+		}
+	})
+}
+
+func TestReplaceBallotOfMissingVoting(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		ballot := model.Ballot{VotingID: uuid.NewString(), UserID: "voter", Choices: []int{0}, Weight: 1, CastAt: time.Now()}
+		if _, _, err := repo.ReplaceBallot(ballot, 1); err == nil {
+			t.Error("saved a ballot of a missing voting")
+		}
+	})
+}
+
+func TestGetBallots(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		voting := mustSave(t, repo, newTestVoting("channel", "A", "B"))
+		for _, userID := range []string{"b", "a"} {
+			ballot := model.Ballot{VotingID: voting.ID, UserID: userID, Choices: []int{1, 0}, Scores: []int{3, -1},
+				Weight: 2, CastAt: time.Unix(1700000000, 0)}
+			if _, _, err := repo.ReplaceBallot(ballot, 0); err != nil {
+				t.Fatalf("replace ballot: %v", err)
+			}
+		}
+
+		ballots, err := repo.GetBallots(voting.ID)
+		if err != nil {
+			t.Fatalf("get ballots: %v", err)
+		}
+		if len(ballots) != 2 || ballots[0].UserID != "a" || ballots[1].UserID != "b" {
+			t.Fatalf("got ballots %+v, want those of a and b", ballots)
+		}
+		if !reflect.DeepEqual(ballots[0].Scores, []int{3, -1}) || ballots[0].Weight != 2 {
+			t.Errorf("got ballot %+v, want scores [3 -1] and weight 2", ballots[0])
+		}
+	})
+}
+
+func TestTransitionVoting(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		voting := newTestVoting("channel", "A", "B")
+		voting.State = model.StateScheduled
+		mustSave(t, repo, voting)
+
+		at := time.Unix(1700000100, 0)
+		deadline := time.Unix(1700003600, 0)
+		open := model.StateTransition{From: model.StateScheduled, To: model.StateOpen, At: at}
+		got, err := repo.TransitionVoting(voting.ID, open, deadline)
+		if err != nil {
+			t.Fatalf("transition: %v", err)
+		}
+		if got.State != model.StateOpen || !got.Deadline.Equal(deadline) || len(got.Transitions) != 1 {
+			t.Errorf("got %+v, want an open voting with one transition", got)
+		}
+
+		_, err = repo.TransitionVoting(voting.ID, open, deadline)
+		assertErrorType(t, err, errors.InvalidTransition)
+
+		closed := model.StateTransition{From: model.StateOpen, To: model.StateClosed, At: at.Add(time.Minute), Members: 12}
+		if got, err = repo.TransitionVoting(voting.ID, closed, time.Time{}); err != nil {
+			t.Fatalf("transition: %v", err)
+		}
+		if !got.ClosedAt.Equal(closed.At) || !got.Deadline.IsZero() {
+			t.Errorf("got closed at %v with deadline %v, want %v without one", got.ClosedAt, got.Deadline, closed.At)
+		}
+		if stored := mustGet(t, repo, voting.ID); stored.ClosedMembers() != closed.Members {
+			t.Errorf("got %d members recorded on closing, want %d", stored.ClosedMembers(), closed.Members)
+		}
+
+		_, err = repo.TransitionVoting(uuid.NewString(), open, deadline)
+		assertErrorType(t, err, errors.NotFound)
+	})
+}
+
+func TestListVotingsByChannel(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		channelID := uuid.NewString()
+		older := newTestVoting(channelID, "A", "B")
+		newer := newTestVoting(channelID, "A", "B")
+		newer.CreatedAt = older.CreatedAt.Add(time.Hour)
+		newer.State = model.StateClosed
+		for _, voting := range []model.Voting{older, newer, newTestVoting(uuid.NewString(), "A", "B")} {
+			mustSave(t, repo, voting)
+		}
+
+		all, err := repo.ListVotingsByChannel(channelID, "", 0, 10)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(all) != 2 || all[0].ID != newer.ID || all[1].ID != older.ID {
+			t.Errorf("listed %d votings, want the newer then the older", len(all))
+		}
+
+		open, err := repo.ListVotingsByChannel(channelID, model.StateOpen, 0, 10)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(open) != 1 || open[0].ID != older.ID {
+			t.Errorf("listed %d open votings, want the older one", len(open))
+		}
+
+		page, err := repo.ListVotingsByChannel(channelID, "", 1, 10)
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+		if len(page) != 1 || page[0].ID != older.ID {
+			t.Errorf("second page has %d votings, want the older one", len(page))
+		}
+	})
+}
+
+func TestFindVotingByPrefix(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		voting := mustSave(t, repo, newTestVoting("channel", "A", "B"))
+
+		got, err := repo.FindVotingByPrefix(voting.ID[:18])
+		if err != nil {
+			t.Fatalf("find: %v", err)
+		}
+		if got.ID != voting.ID {
+			t.Errorf("found %s, want %s", got.ID, voting.ID)
+		}
+
+		_, err = repo.FindVotingByPrefix("zzzz")
+		assertErrorType(t, err, errors.NotFound)
+	})
+}
+
+func TestWeightTables(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		channelID := uuid.NewString()
+		entries := []model.WeightEntry{
+			{ChannelID: channelID, Table: "board", Subject: model.WeightUser, Name: "alice", Weight: 2},
+			{ChannelID: channelID, Table: "board", Subject: model.WeightRole, Name: "channel_admin", Weight: 3},
+			{ChannelID: channelID, Table: "audit", Subject: model.WeightGroup, Name: "auditors", Weight: 0.5},
+			{ChannelID: channelID, Table: "board", Subject: model.WeightUser, Name: "alice", Weight: 4},
+		}
+		for _, entry := range entries {
+			if err := repo.SetWeight(entry); err != nil {
+				t.Fatalf("set weight: %v", err)
+			}
+		}
+
+		tables, err := repo.ListWeightTables(channelID)
+		if err != nil {
+			t.Fatalf("list tables: %v", err)
+		}
+		if !reflect.DeepEqual(tables, []string{"audit", "board"}) {
+			t.Errorf("listed tables %v, want [audit board]", tables)
+		}
+
+		board, err := repo.GetWeightTable(channelID, "board")
+		if err != nil {
+			t.Fatalf("get table: %v", err)
+		}
+		weights := make(map[string]float64)
+		for _, entry := range board {
+			weights[entry.Name] = entry.Weight
+		}
+		if want := map[string]float64{"alice": 4, "channel_admin": 3}; !reflect.DeepEqual(weights, want) {
+			t.Errorf("board weights are %v, want %v", weights, want)
+		}
+	})
+}
+
+func TestSearchVotings(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		// A term of its own keeps other tests' votings out of the results.
+		term := "z" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		visible, hidden := uuid.NewString(), uuid.NewString()
+
+		// The hidden channel's votings match better and outnumber the limit,
+		// so filtering after capping the candidates would return nothing.
+		for i := 0; i < 5; i++ {
+			voting := newTestVoting(hidden, term, term+" "+term)
+			voting.Question = term + " " + term
+			mustSave(t, repo, voting)
+		}
+		open := newTestVoting(visible, "Да", "Нет")
+		open.Question = "Обед " + term
+		mustSave(t, repo, open)
+		closed := newTestVoting(visible, "Да", "Нет")
+		closed.Question = "Ужин " + term
+		closed.State = model.StateClosed
+		mustSave(t, repo, closed)
+		draft := newTestVoting(visible, "Да", "Нет")
+		draft.Question = "Завтрак " + term
+		draft.State = model.StateDraft
+		mustSave(t, repo, draft)
+
+		ids := func(votings []model.Voting) []string {
+			var ids []string
+			for _, voting := range votings {
+				ids = append(ids, voting.ID)
+			}
+			return ids
+		}
+		mine := []string{visible, uuid.NewString()}
+
+		tests := []struct {
+			name   string
+			search VotingSearch
+			want   []string
+		}{
+			{name: "visible", search: VotingSearch{Terms: []string{term}, ChannelIDs: mine, Limit: 3}, want: []string{open.ID}},
+			{name: "closed too", search: VotingSearch{Terms: []string{term}, ChannelIDs: mine, IncludeClosed: true, Limit: 3},
+				want: []string{closed.ID, open.ID}},
+			{name: "channel", search: VotingSearch{Terms: []string{term}, ChannelIDs: []string{visible}, Limit: 1}, want: []string{open.ID}},
+			{name: "excluded", search: VotingSearch{Terms: []string{term}, ExcludeChannelIDs: []string{hidden}, Limit: 1}, want: []string{open.ID}},
+		}
+		for _, tt := range tests {
+			votings, err := repo.SearchVotings(tt.search)
+			if err != nil {
+				t.Fatalf("%s: search: %v", tt.name, err)
+			}
+			got := ids(votings)
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: found %v, want %v", tt.name, got, want)
+			}
+		}
+	})
+}
+
+// Postings of channels outside the search are dropped before the per-term
+// cap, so they can't crowd a match out however many come first.
+func TestSearchVotingsSkipsOtherChannelsBeforeCap(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo VotingRepository) {
+		term := "z" + strings.ReplaceAll(uuid.NewString(), "-", "")
+		visible, hidden := uuid.NewString(), uuid.NewString()
+
+		// Postings are read in term and voting ID order; the hidden ones
+		// have the lower IDs and are saved first.
+		for i := 0; i <= maxTermPostings; i++ {
+			voting := newTestVoting(hidden, "Да", "Нет")
+			voting.ID = fmt.Sprintf("0%07d-%s", i, uuid.NewString()[9:])
+			voting.Question = term
+			mustSave(t, repo, voting)
+		}
+		match := newTestVoting(visible, "Да", "Нет")
+		match.ID = "f" + match.ID[1:]
+		match.Question = term
+		mustSave(t, repo, match)
+
+		votings, err := repo.SearchVotings(VotingSearch{Terms: []string{term}, ChannelIDs: []string{visible}, Limit: 10})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(votings) != 1 || votings[0].ID != match.ID {
+			t.Errorf("found %d votings, want only %s", len(votings), match.ID)
+		}
+	})
+}
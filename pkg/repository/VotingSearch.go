@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"go-voting-bot/pkg/model"
+	"slices"
+	"sort"
+)
+
+// VotingSearch describes a full-text search over voting questions and options.
+type VotingSearch struct {
+	Terms []string
+	// ChannelIDs limits the search to these channels when not empty.
+	ChannelIDs []string
+	// ExcludeChannelIDs leaves these channels out of the search.
+	ExcludeChannelIDs []string
+	// IncludeClosed adds closed and archived votings to the open ones.
+	IncludeClosed bool
+	Limit         int
+}
+
+const maxTermPostings = 1000
+
+// searchHit is a voting matched by a query term, with its channel.
+type searchHit struct {
+	ChannelID string
+	Weight    int
+}
+
+type searchCandidate struct {
+	VotingID  string
+	ChannelID string
+	Matched   int
+	Score     int
+}
+
+// rankSearchCandidates combines the postings of every query term. Postings
+// must already be filtered by state and channel, see VotingSearch.matches,
+// so the candidates kept up to the search limit are the results. Votings
+// matching more distinct terms rank first, then those with a higher weight.
+func rankSearchCandidates(search VotingSearch, postings func(prefix string) (map[string]searchHit, error)) ([]searchCandidate, error) {
+	byID := make(map[string]*searchCandidate)
+	for _, term := range search.Terms {
+		hits, err := postings(term)
+		if err != nil {
+			return nil, err
+		}
+		for votingID, hit := range hits {
+			candidate, ok := byID[votingID]
+			if !ok {
+				candidate = &searchCandidate{VotingID: votingID, ChannelID: hit.ChannelID}
+				byID[votingID] = candidate
+			}
+			candidate.Matched++
+			candidate.Score += hit.Weight
+		}
+	}
+
+	candidates := make([]searchCandidate, 0, len(byID))
+	for _, candidate := range byID {
+		candidates = append(candidates, *candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Matched != b.Matched {
+			return a.Matched > b.Matched
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.VotingID < b.VotingID
+	})
+	if len(candidates) > search.Limit {
+		candidates = candidates[:search.Limit]
+	}
+	return candidates, nil
+}
+
+// collectSearchResults loads ranked candidates in one batch and returns them
+// in rank order. getVotings skips the IDs it doesn't find; votings deleted or
+// changed since their postings were read are left out.
+func collectSearchResults(search VotingSearch, candidates []searchCandidate, getVotings func(votingIDs []string) ([]model.Voting, error)) ([]model.Voting, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.VotingID
+	}
+	loaded, err := getVotings(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.Voting, len(loaded))
+	for _, voting := range loaded {
+		byID[voting.ID] = voting
+	}
+
+	var votings []model.Voting
+	for _, candidate := range candidates {
+		voting, ok := byID[candidate.VotingID]
+		if ok && search.matches(voting.State, voting.ChannelID) && voting.ChannelID == candidate.ChannelID {
+			votings = append(votings, voting)
+		}
+	}
+	return votings, nil
+}
+
+// states lists the voting states the search returns: drafts and scheduled
+// votings are not published yet.
+func (search VotingSearch) states() []string {
+	states := []string{string(model.StateOpen)}
+	if search.IncludeClosed {
+		states = append(states, string(model.StateClosed), string(model.StateArchived))
+	}
+	return states
+}
+
+// matches reports whether a voting passes the state and channel filters of
+// the search.
+func (search VotingSearch) matches(state model.VotingState, channelID string) bool {
+	if !slices.Contains(search.states(), string(state)) {
+		return false
+	}
+	if slices.Contains(search.ExcludeChannelIDs, channelID) {
+		return false
+	}
+	return len(search.ChannelIDs) == 0 || slices.Contains(search.ChannelIDs, channelID)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"strings"
+
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// CommandReplies collects what the handling of one slash command posts to
+// its channel, to be given back as the command response.
+type CommandReplies struct {
+	ChannelID string
+	UserID    string
+
+	public      []string
+	messages    []string
+	attachments []*mattermodel.SlackAttachment
+}
+
+// WithReplies returns a copy of the service that holds back the messages
+// of a slash command in the replies instead of posting them.
+func (s *VotingService) WithReplies(replies *CommandReplies) *VotingService {
+	scoped := *s
+	scoped.replies = replies
+	return &scoped
+}
+
+// Response gives the collected messages back as a command response. The
+// public messages are posted in the channel, and the ephemeral ones follow
+// them as an extra response only the user sees.
+func (r *CommandReplies) Response() *mattermodel.CommandResponse {
+	ephemeral := &mattermodel.CommandResponse{
+		ResponseType: mattermodel.CommandResponseTypeEphemeral,
+		Text:         strings.Join(r.messages, "\n\n"),
+		Attachments:  r.attachments,
+	}
+	if len(r.public) == 0 {
+		return ephemeral
+	}
+	response := &mattermodel.CommandResponse{
+		ResponseType: mattermodel.CommandResponseTypeInChannel,
+		Text:         strings.Join(r.public, "\n\n"),
+	}
+	if ephemeral.Text != "" || len(ephemeral.Attachments) > 0 {
+		response.ExtraResponses = []*mattermodel.CommandResponse{ephemeral}
+	}
+	return response
+}
+
+// EphemeralText joins the collected ephemeral messages, for answers such
+// as that to an integration action, which only the user sees.
+func (r *CommandReplies) EphemeralText() string {
+	return strings.Join(r.messages, "\n\n")
+}
+
+// capturePost holds back a message to the command's channel.
+func (r *CommandReplies) capturePost(channelID, message string) bool {
+	if r == nil || r.ChannelID != channelID {
+		return false
+	}
+	r.public = append(r.public, message)
+	return true
+}
+
+// captureEphemeral holds back an ephemeral message to the user of the
+// command in its channel.
+func (r *CommandReplies) captureEphemeral(channelID, userID, message string, attachments []*mattermodel.SlackAttachment) bool {
+	if r == nil || r.ChannelID != channelID || r.UserID != userID {
+		return false
+	}
+	r.messages = append(r.messages, message)
+	r.attachments = append(r.attachments, attachments...)
+	return true
+}
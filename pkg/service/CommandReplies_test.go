@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// A command's replies hold only the messages of its own handling: other
+// users and channels, and the shared service, still get posts.
+func TestCommandRepliesAreScopedToTheCommand(t *testing.T) {
+	s, _ := newMemoryService()
+	replies := &CommandReplies{ChannelID: "channel", UserID: "user"}
+	scoped := s.WithReplies(replies)
+
+	scoped.PostEphemeralMessage("channel", "user", "только вам")
+	scoped.PostMessage("channel", "всем")
+	scoped.PostEphemeralMessage("channel", "other", "другому")
+	scoped.PostMessage("elsewhere", "в другой канал")
+	s.PostEphemeralMessage("channel", "user", "от общего сервиса")
+
+	response := replies.Response()
+	if response.ResponseType != mattermodel.CommandResponseTypeInChannel || response.Text != "всем" {
+		t.Errorf("got response %q %q, want the public message in the channel", response.ResponseType, response.Text)
+	}
+	if len(response.ExtraResponses) != 1 || response.ExtraResponses[0].Text != "только вам" ||
+		response.ExtraResponses[0].ResponseType != mattermodel.CommandResponseTypeEphemeral {
+		t.Errorf("got extra responses %+v, want the ephemeral message alone", response.ExtraResponses)
+	}
+	if text := replies.EphemeralText(); text != "только вам" {
+		t.Errorf("got ephemeral text %q, want the ephemeral message alone", text)
+	}
+
+	quiet := (&CommandReplies{ChannelID: "channel", UserID: "user"}).Response()
+	if quiet.ResponseType != mattermodel.CommandResponseTypeEphemeral || quiet.Text != "" {
+		t.Errorf("got response %+v for no messages, want an empty ephemeral one", quiet)
+	}
+}
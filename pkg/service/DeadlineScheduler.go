@@ -0,0 +1,81 @@
+package service
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DeadlineScheduler runs a callback when a voting is due to change state by
+// itself: a scheduled voting opens or an open one reaches its deadline. It
+// keeps one timer per voting; times already in the past fire at once.
+type DeadlineScheduler struct {
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+	due    func(votingID string)
+	Logger *slog.Logger
+}
+
+func NewDeadlineScheduler(due func(votingID string), logger *slog.Logger) *DeadlineScheduler {
+	return &DeadlineScheduler{
+		timers: make(map[string]*time.Timer),
+		due:    due,
+		Logger: logger,
+	}
+}
+
+// Schedule arranges for the voting to be due at deadline, replacing any
+// time scheduled for it before.
+func (d *DeadlineScheduler) Schedule(votingID string, deadline time.Time) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if timer, ok := d.timers[votingID]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(deadline), func() {
+		d.mu.Lock()
+		if d.timers[votingID] == timer {
+			delete(d.timers, votingID)
+		}
+		d.mu.Unlock()
+
+		d.Logger.Info("Voting is due", slog.String("voting_id", votingID))
+		d.due(votingID)
+	})
+	d.timers[votingID] = timer
+}
+
+// Cancel forgets the pending time of a voting, e.g. one closed or deleted
+// before it.
+func (d *DeadlineScheduler) Cancel(votingID string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if timer, ok := d.timers[votingID]; ok {
+		timer.Stop()
+		delete(d.timers, votingID)
+	}
+}
+
+// Stop cancels every pending deadline. They are reloaded from the repository
+// on the next start.
+func (d *DeadlineScheduler) Stop() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for votingID, timer := range d.timers {
+		timer.Stop()
+		delete(d.timers, votingID)
+	}
+}
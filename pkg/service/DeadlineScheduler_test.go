@@ -0,0 +1,98 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/model"
+	"testing"
+	"time"
+)
+
+// scheduleAdvances makes the service's scheduler advance due votings and
+// report each one it advanced.
+func scheduleAdvances(t *testing.T, s *VotingService) <-chan model.Voting {
+	advanced := make(chan model.Voting, 10)
+	s.Deadlines = NewDeadlineScheduler(func(votingID string) {
+		voting, err := s.AdvanceVoting(votingID)
+		if err != nil {
+			t.Errorf("advance %s: %v", votingID, err)
+			return
+		}
+		advanced <- voting
+	}, s.Logger)
+	t.Cleanup(s.Deadlines.Stop)
+	return advanced
+}
+
+func waitAdvanced(t *testing.T, advanced <-chan model.Voting, votingID string, want model.VotingState) {
+	t.Helper()
+	select {
+	case voting := <-advanced:
+		if voting.ID != votingID || voting.State != want {
+			t.Errorf("voting %s became %s, want %s to become %s", voting.ID, voting.State, votingID, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("voting %s did not become %s", votingID, want)
+	}
+}
+
+// A deadline that passed while the bot was down closes the voting as soon
+// as the pending votings are scheduled, at its deadline.
+func TestSchedulePendingClosesOverdueVotings(t *testing.T) {
+	s, repo := newMemoryService()
+	advanced := scheduleAdvances(t, s)
+	deadline := time.Now().Add(-time.Hour).Truncate(time.Second)
+	overdue := saveTestVoting(t, repo, model.StateOpen, func(v *model.Voting) { v.Deadline = deadline })
+	saveTestVoting(t, repo, model.StateOpen, func(v *model.Voting) { v.Deadline = time.Now().Add(time.Hour) })
+
+	if err := s.SchedulePending(); err != nil {
+		t.Fatalf("schedule pending: %v", err)
+	}
+	waitAdvanced(t, advanced, overdue.ID, model.StateClosed)
+
+	stored, _ := repo.GetVoting(overdue.ID)
+	if !stored.ClosedAt.Equal(deadline) {
+		t.Errorf("closed at %v, want the deadline %v", stored.ClosedAt, deadline)
+	}
+	select {
+	case voting := <-advanced:
+		t.Errorf("voting %s advanced before its deadline", voting.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// Reopening a closed voting with a deadline arms its timer again, and the
+// voting closes once more when it fires.
+func TestReopenedVotingClosesAgain(t *testing.T) {
+	s, repo := newMemoryService()
+	advanced := scheduleAdvances(t, s)
+	voting := saveTestVoting(t, repo, model.StateClosed)
+
+	reopened, err := s.ReopenVoting(dto.VotingRequest{Text: voting.ID + " --duration 2h"}, "channel", voting.CreatorID)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if !hasTimer(s, voting.ID) {
+		t.Fatal("reopening with a deadline did not arm a timer")
+	}
+
+	// Bring the deadline close instead of waiting two hours; closing by
+	// hand disarms the timer.
+	closed, err := s.transition(reopened, model.StateClosed, time.Now(), time.Time{})
+	if err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if hasTimer(s, voting.ID) {
+		t.Error("closing by hand left the timer armed")
+	}
+	if _, err := s.transition(closed, model.StateOpen, time.Now(), time.Now().Add(50*time.Millisecond)); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	waitAdvanced(t, advanced, voting.ID, model.StateClosed)
+}
+
+func hasTimer(s *VotingService, votingID string) bool {
+	s.Deadlines.mu.Lock()
+	defer s.Deadlines.mu.Unlock()
+	_, ok := s.Deadlines.timers[votingID]
+	return ok
+}
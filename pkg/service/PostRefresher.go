@@ -0,0 +1,87 @@
+package service
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// PostRefreshDelay is how long a voting card waits for more votes before it
+// is patched.
+const PostRefreshDelay = 3 * time.Second
+
+// PostRefresher coalesces the updates of voting cards. The first change of
+// a voting schedules one refresh after the delay, and the changes that come
+// before it fires ride along, so a burst of votes patches the card once.
+type PostRefresher struct {
+	mu      sync.Mutex
+	timers  map[string]*time.Timer
+	delay   time.Duration
+	refresh func(votingID string)
+	Logger  *slog.Logger
+}
+
+func NewPostRefresher(refresh func(votingID string), delay time.Duration, logger *slog.Logger) *PostRefresher {
+	return &PostRefresher{
+		timers:  make(map[string]*time.Timer),
+		delay:   delay,
+		refresh: refresh,
+		Logger:  logger,
+	}
+}
+
+// Touch marks the card of the voting as out of date.
+func (p *PostRefresher) Touch(votingID string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.timers[votingID]; ok {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(p.delay, func() {
+		p.mu.Lock()
+		if p.timers[votingID] != timer {
+			p.mu.Unlock()
+			return
+		}
+		delete(p.timers, votingID)
+		p.mu.Unlock()
+
+		p.refresh(votingID)
+	})
+	p.timers[votingID] = timer
+}
+
+// Flush refreshes the card of the voting now, taking the place of a pending
+// refresh, e.g. when the voting closes.
+func (p *PostRefresher) Flush(votingID string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if timer, ok := p.timers[votingID]; ok {
+		timer.Stop()
+		delete(p.timers, votingID)
+	}
+	p.mu.Unlock()
+
+	p.refresh(votingID)
+}
+
+// Stop drops the pending refreshes.
+func (p *PostRefresher) Stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for votingID, timer := range p.timers {
+		timer.Stop()
+		delete(p.timers, votingID)
+	}
+}
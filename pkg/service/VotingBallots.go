@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/utils"
+	"slices"
+	"strings"
+)
+
+// voterKey is what the voter's ballot is stored under: the user ID, or in an
+// anonymous voting a keyed hash of it, so that one vote per user is still
+// enforced without the store knowing who voted.
+func (s *VotingService) voterKey(voting model.Voting, channelID, userID string) (string, error) {
+	if !voting.Settings.Anonymous {
+		return userID, nil
+	}
+	if len(s.BallotSecret) == 0 {
+		s.PostEphemeralMessage(channelID, userID, "Анонимные голосования сейчас недоступны: у бота не задан BALLOT_SECRET.")
+		err := errors.BadRequest.New(errors.UnavailableResource.Message())
+		err = errors.AddErrorContext(err, voting.ID, "BALLOT_SECRET is not set for anonymous voting")
+		return "", err
+	}
+	return utils.VoterKey(s.BallotSecret, voting.ID, userID), nil
+}
+
+// parseChoices reads the option numbers of a vote: picked ones separated by
+// commas, or a ranking separated by ">" in a ranked voting.
+func (s *VotingService) parseChoices(voting model.Voting, text, channelID, userID string) ([]int, error) {
+	ranked := voting.Settings.IsRanked()
+	var optionNumbers []int
+	var err error
+	if ranked {
+		optionNumbers, err = utils.ParseRanking(text)
+	} else {
+		optionNumbers, err = utils.ParseChoices(text)
+	}
+	if err != nil {
+		if ranked {
+			s.PostEphemeralMessage(channelID, userID, "Расставьте номера вариантов по предпочтению через >, например 3>1>2.")
+			err = errors.BadRequest.Wrapf(err, errors.WrongType.Message())
+			err = errors.AddErrorContext(err, "answer", "wrong ranking format, should be integers separated by >")
+			return nil, err
+		}
+		s.PostEphemeralMessage(channelID, userID, "Номера вариантов должны быть числами через запятую, например 1,3.")
+		err = errors.BadRequest.Wrapf(err, errors.WrongType.Message())
+		err = errors.AddErrorContext(err, "answer", "wrong answers format, should be comma-separated integers")
+		return nil, err
+	}
+
+	if limit := voting.Settings.ChoiceLimit(); !ranked && len(optionNumbers) > limit {
+		s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("В этом голосовании можно выбрать не больше %d %s.",
+			limit, utils.Plural(limit, "варианта", "вариантов", "вариантов")))
+		err = errors.BadRequest.New(errors.BadRequest.Message())
+		err = errors.AddErrorContext(err, "answer", "Too many answer variants")
+		return nil, err
+	}
+
+	choices := make([]int, len(optionNumbers))
+	for i, optionNumber := range optionNumbers {
+		if optionNumber < 1 || optionNumber > len(voting.Options) {
+			s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Неверный номер варианта: %d.", optionNumber))
+			err = errors.BadRequest.New(errors.BadRequest.Message())
+			err = errors.AddErrorContext(err, "answer", "Wrong answer variant")
+			return nil, err
+		}
+		choices[i] = optionNumber - 1
+	}
+	if !ranked {
+		slices.Sort(choices)
+	}
+	return choices, nil
+}
+
+// parseScores reads the scores of a vote in a score voting, one for every
+// option in order. Choices then list every option.
+func (s *VotingService) parseScores(voting model.Voting, text, channelID, userID string) ([]int, []int, error) {
+	settings := voting.Settings
+	scores, err := utils.ParseScores(text)
+	if err == nil && len(scores) != len(voting.Options) {
+		err = fmt.Errorf("got %d scores for %d options", len(scores), len(voting.Options))
+	}
+	if err != nil {
+		options := len(voting.Options)
+		s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Оцените каждый из %d %s по порядку через запятую, например %s.",
+			options, utils.Plural(options, "варианта", "вариантов", "вариантов"), utils.ScoreExample(settings.MinScore, settings.MaxScore, options)))
+		err = errors.BadRequest.Wrapf(err, errors.WrongType.Message())
+		err = errors.AddErrorContext(err, "answer", "wrong scores format, should be one comma-separated integer per option")
+		return nil, nil, err
+	}
+
+	choices := make([]int, len(scores))
+	for i, score := range scores {
+		if score < settings.MinScore || score > settings.MaxScore {
+			s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Оценка должна быть от %d до %d, а не %d.",
+				settings.MinScore, settings.MaxScore, score))
+			err = errors.BadRequest.New(errors.BadRequest.Message())
+			err = errors.AddErrorContext(err, "answer", "Score out of range")
+			return nil, nil, err
+		}
+		choices[i] = i
+	}
+	return choices, scores, nil
+}
+
+// ballotLabels describes a vote: the chosen options, or every option with
+// its score in a score voting.
+func ballotLabels(voting model.Voting, ballot model.Ballot) string {
+	if ballot.Scores == nil {
+		return choiceLabels(voting, ballot.Choices)
+	}
+	labels := make([]string, len(ballot.Choices))
+	for i, option := range ballot.Choices {
+		labels[i] = fmt.Sprintf("%s — %d", optionLabel(voting, option), ballot.Scores[i])
+	}
+	return strings.Join(labels, ", ")
+}
+
+// choiceLabels lists the chosen options in the order they were chosen; a
+// ranking is joined with " > ".
+func choiceLabels(voting model.Voting, choices []int) string {
+	labels := optionLabels(voting, choices)
+	if voting.Settings.IsRanked() {
+		return strings.Join(labels, " > ")
+	}
+	return strings.Join(labels, ", ")
+}
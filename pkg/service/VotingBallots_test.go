@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/repository"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// anonymityBackend is a storage together with a way to read everything it
+// holds about the ballots of a voting, bypassing the repository decoding.
+type anonymityBackend struct {
+	name string
+	open func(t *testing.T, logger *slog.Logger) (repository.VotingRepository, func(votingID string) string)
+}
+
+func anonymityBackends() []anonymityBackend {
+	return []anonymityBackend{
+		{name: "memory", open: func(t *testing.T, logger *slog.Logger) (repository.VotingRepository, func(string) string) {
+			repo := repository.NewMemoryRepository()
+			return repo, func(votingID string) string {
+				ballots, err := repo.GetBallots(votingID)
+				if err != nil {
+					t.Fatalf("get ballots: %v", err)
+				}
+				return fmt.Sprintf("%+v", ballots)
+			}
+		}},
+		{name: "sqlite", open: func(t *testing.T, logger *slog.Logger) (repository.VotingRepository, func(string) string) {
+			repo, err := repository.NewSQLRepository(repository.DriverSQLite, filepath.Join(t.TempDir(), "voting.db"), logger)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			t.Cleanup(func() { repo.Close() })
+			if err := repo.Migrate(); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			return repo, func(votingID string) string {
+				var raw strings.Builder
+				for _, table := range []string{"ballots", "ballot_choices"} {
+					rows, err := repo.DB.Query(`SELECT * FROM `+table+` WHERE voting_id = ?`, votingID)
+					if err != nil {
+						t.Fatalf("read %s: %v", table, err)
+					}
+					columns, _ := rows.Columns()
+					for rows.Next() {
+						values := make([]interface{}, len(columns))
+						pointers := make([]interface{}, len(columns))
+						for i := range values {
+							pointers[i] = &values[i]
+						}
+						if err := rows.Scan(pointers...); err != nil {
+							t.Fatalf("read %s: %v", table, err)
+						}
+						fmt.Fprintf(&raw, "%s %v\n", table, values)
+					}
+					rows.Close()
+				}
+				return raw.String()
+			}
+		}},
+		{name: "tarantool", open: func(t *testing.T, logger *slog.Logger) (repository.VotingRepository, func(string) string) {
+			host := os.Getenv("TEST_TARANTOOL_HOST")
+			if host == "" {
+				t.Skip("TEST_TARANTOOL_HOST is not set")
+			}
+			repo, err := repository.NewTarantoolClient(host, os.Getenv("TEST_TARANTOOL_PORT"),
+				os.Getenv("TEST_TARANTOOL_USER"), os.Getenv("TEST_TARANTOOL_PASSWORD"), logger)
+			if err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			t.Cleanup(func() { repo.Close() })
+			if err := repo.Migrate(); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+			return repo, func(votingID string) string {
+				var tuples []interface{}
+				err := repo.Conn.SelectTyped("ballots", "primary", 0, 100, tarantool.IterEq, []interface{}{votingID}, &tuples)
+				if err != nil {
+					t.Fatalf("read ballots: %v", err)
+				}
+				return fmt.Sprintf("%v", tuples)
+			}
+		}},
+	}
+}
+
+// An anonymous ballot must not reveal the voter: neither the stored rows
+// nor the logs may hold the user ID, and the stored ballot has no time.
+func TestAnonymousBallotsHideVoters(t *testing.T) {
+	for _, backend := range anonymityBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			repo, rawBallots := backend.open(t, logger)
+			s := &VotingService{
+				Client:       mattermodel.NewAPIv4Client("http://localhost"),
+				VoteRepo:     repo,
+				BallotSecret: []byte("secret"),
+				Logger:       logger,
+			}
+
+			voting := saveTestVoting(t, repo, model.StateOpen, func(voting *model.Voting) {
+				voting.Settings = model.VotingSettings{Anonymous: true, MaxChoices: 2}
+			})
+
+			userID := "userid" + strings.ReplaceAll(uuid.NewString(), "-", "")
+			for _, option := range []int{0, 1} {
+				if _, err := s.VoteOption(voting.ID, option, voting.ChannelID, userID); err != nil {
+					t.Fatalf("vote: %v", err)
+				}
+			}
+			raw := rawBallots(voting.ID)
+			if raw == "" || raw == "[]" {
+				t.Fatal("no ballot was stored")
+			}
+			if strings.Contains(raw, userID) {
+				t.Errorf("stored ballot holds the user ID: %s", raw)
+			}
+
+			ballots, err := repo.GetBallots(voting.ID)
+			if err != nil {
+				t.Fatalf("get ballots: %v", err)
+			}
+			if len(ballots) != 1 || !ballots[0].CastAt.IsZero() {
+				t.Errorf("got ballots %+v, want one without a cast time", ballots)
+			}
+
+			// Unpicking both options retracts the ballot.
+			for _, option := range []int{0, 1} {
+				if _, err := s.VoteOption(voting.ID, option, voting.ChannelID, userID); err != nil {
+					t.Fatalf("unvote: %v", err)
+				}
+			}
+			if ballots, _ := repo.GetBallots(voting.ID); len(ballots) != 0 {
+				t.Errorf("got ballots %+v after retracting", ballots)
+			}
+			if strings.Contains(logs.String(), userID) {
+				t.Errorf("logs hold the user ID:\n%s", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"testing"
+)
+
+// Results and voters of a voting are only shown in the voting's channel.
+func TestResultsAndVotersStayInTheirChannel(t *testing.T) {
+	s, repo := newMemoryService()
+	voting := saveTestVoting(t, repo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings = model.VotingSettings{Public: true, MaxChoices: 1}
+	})
+	request := dto.VotingRequest{Text: voting.ID}
+
+	if _, _, err := s.GetResultsByVotingId(request, voting.ChannelID, "user"); err != nil {
+		t.Errorf("results in the voting's channel: %v", err)
+	}
+	if _, err := s.VotingVoters(request, voting.ChannelID, "user"); err != nil {
+		t.Errorf("voters in the voting's channel: %v", err)
+	}
+
+	_, _, err := s.GetResultsByVotingId(request, "other", "user")
+	if errors.GetType(err) != errors.Forbidden || errors.GetErrorContext(err)["field"] != ForeignChannelField {
+		t.Errorf("results in another channel: got %v, want a foreign channel error", err)
+	}
+	_, err = s.VotingVoters(request, "other", "user")
+	if errors.GetType(err) != errors.Forbidden || errors.GetErrorContext(err)["field"] != ForeignChannelField {
+		t.Errorf("voters in another channel: got %v, want a foreign channel error", err)
+	}
+}
+
+// Votes are only taken in the voting's channel, whose members its quorum
+// counts.
+func TestVotesStayInTheirChannel(t *testing.T) {
+	s, repo := newMemoryService()
+	voting := saveTestVoting(t, repo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings = model.VotingSettings{MaxChoices: 1, Quorum: 0.6}
+	})
+
+	_, err := s.AddNewVote(dto.VotingRequest{Text: voting.ID + " 1"}, "other", "user")
+	if errors.GetType(err) != errors.Forbidden || errors.GetErrorContext(err)["field"] != ForeignChannelField {
+		t.Errorf("vote in another channel: got %v, want a foreign channel error", err)
+	}
+	_, err = s.VoteOption(voting.ID, 0, "other", "user")
+	if errors.GetType(err) != errors.Forbidden {
+		t.Errorf("vote button in another channel: got %v, want a foreign channel error", err)
+	}
+	_, err = s.RemoveVote(dto.VotingRequest{Text: voting.ID}, "other", "user")
+	if errors.GetType(err) != errors.Forbidden {
+		t.Errorf("unvote in another channel: got %v, want a foreign channel error", err)
+	}
+
+	if _, err := repo.GetBallot(voting.ID, "user"); errors.GetType(err) != errors.NotFound {
+		t.Errorf("ballot from another channel: got %v, want none stored", err)
+	}
+}
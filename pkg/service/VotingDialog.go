@@ -0,0 +1,118 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/utils"
+	"log/slog"
+	"strings"
+	"time"
+
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// CreateDialogVoting creates and opens a voting from the poll creation
+// dialog. Unlike AddNewVoting it posts nothing: the messages for invalid
+// fields are returned keyed by field name, for the dialog to show.
+func (s *VotingService) CreateDialogVoting(request dto.VotingDialogRequest, channelID, userID string) (model.Voting, map[string]string, error) {
+	fieldErrors := make(map[string]string)
+
+	question := strings.TrimSpace(request.Question)
+	if question == "" {
+		fieldErrors[dto.DialogQuestion] = "Укажите вопрос."
+	}
+	var options []string
+	for _, line := range strings.Split(request.Options, "\n") {
+		if option := strings.TrimSpace(line); option != "" {
+			options = append(options, option)
+		}
+	}
+	if len(options) < 2 {
+		fieldErrors[dto.DialogOptions] = "Укажите как минимум два варианта, каждый с новой строки."
+	}
+
+	var settings model.VotingSettings
+	switch request.Kind {
+	case "", dto.DialogKindSingle:
+	case dto.DialogKindMultiple:
+		settings.MaxChoices = len(options)
+	case dto.DialogKindRanked:
+		settings.Kind = model.KindRanked
+	case dto.DialogKindSchulze:
+		settings.Kind, settings.Tally = model.KindRanked, model.TallySchulze
+	case dto.DialogKindScore:
+		settings.Kind, settings.MinScore, settings.MaxScore = model.KindScore, 1, 5
+	default:
+		fieldErrors[dto.DialogKind] = "Выберите тип голосования из списка."
+	}
+
+	now := time.Now()
+	deadline, err := dialogDeadline(strings.TrimSpace(request.Deadline), now)
+	if err != nil {
+		fieldErrors[dto.DialogDeadline] = "Неверный срок голосования: " + err.Error()
+	}
+
+	visibility, ok := resultsVisibilities[request.Results]
+	if !ok {
+		fieldErrors[dto.DialogResults] = "Выберите, кому видны результаты, из списка."
+	}
+	settings.Results = visibility
+
+	if request.Anonymous {
+		if len(s.BallotSecret) == 0 {
+			fieldErrors[dto.DialogAnonymous] = "Анонимные голосования не настроены: администратору бота нужно задать BALLOT_SECRET."
+		}
+		settings.Anonymous = true
+	}
+
+	if len(fieldErrors) > 0 {
+		s.Logger.Info("Poll dialog rejected", slog.String("channel_id", channelID), slog.String("user_id", userID),
+			slog.Any("fields", fieldErrors))
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "dialog", "invalid poll dialog fields")
+		return model.Voting{}, fieldErrors, err
+	}
+
+	voting, err := s.saveNewVoting(model.Voting{
+		CreatorID: userID,
+		ChannelID: channelID,
+		Question:  question,
+		Options:   options,
+		CreatedAt: now,
+		State:     model.StateOpen,
+		Deadline:  deadline,
+		Settings:  settings,
+	})
+	return voting, nil, err
+}
+
+// dialogDeadline reads the deadline field, which takes either a duration,
+// as --duration does, or a closing time, as --until does.
+func dialogDeadline(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	deadline, err := utils.ParseDeadline(value, "", now)
+	if err == nil {
+		return deadline, nil
+	}
+	// Only times have dates and clock times in them.
+	if strings.ContainsAny(value, "-.:") {
+		return utils.ParseDeadline("", value, now)
+	}
+	return time.Time{}, err
+}
+
+// OpenDialog opens an interactive dialog for the user whose click or
+// command gave the trigger ID.
+func (s *VotingService) OpenDialog(request mattermodel.OpenDialogRequest) error {
+	_, err := s.Client.OpenInteractiveDialog(request)
+	if err != nil {
+		s.Logger.Error("Failed to open dialog in Mattermost", slog.String("callback_id", request.Dialog.CallbackId),
+			slog.Any("error", err))
+		err = errors.UnavailableResource.Wrapf(err, errors.UnavailableResource.Message())
+		return errors.AddErrorContext(err, "trigger_id", "Failed to open dialog in Mattermost")
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"fmt"
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/utils"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// OpenVoting publishes a draft or opens a scheduled voting early. A new
+// deadline may be given with --duration or --until.
+func (s *VotingService) OpenVoting(request dto.VotingRequest, channelID, userID string) (model.Voting, error) {
+	voting, flags, err := s.creatorVoting(request, "open", channelID, userID)
+	if err != nil {
+		return model.Voting{}, err
+	}
+
+	if voting.State != model.StateDraft && voting.State != model.StateScheduled {
+		err := illegalTransition(voting, model.StateOpen)
+		s.postTransitionRefused(channelID, userID, voting, err, "открыть")
+		return model.Voting{}, err
+	}
+
+	now := time.Now()
+	deadline, err := s.commandDeadline(flags, voting.Deadline, now, channelID, userID)
+	if err != nil {
+		return model.Voting{}, err
+	}
+	if isExpired(model.Voting{Deadline: deadline}, now) {
+		s.PostEphemeralMessage(channelID, userID, "Срок голосования уже прошёл. Укажите новый: --duration или --until.")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "deadline", "Deadline has already passed")
+		return model.Voting{}, err
+	}
+
+	opened, err := s.transition(voting, model.StateOpen, now, deadline)
+	if err != nil {
+		s.postTransitionRefused(channelID, userID, voting, err, "открыть")
+		return model.Voting{}, err
+	}
+	return opened, nil
+}
+
+// ReopenVoting opens a closed voting again, optionally with a new deadline.
+// Without one the reopened voting stays open until closed by hand.
+func (s *VotingService) ReopenVoting(request dto.VotingRequest, channelID, userID string) (model.Voting, error) {
+	voting, flags, err := s.creatorVoting(request, "reopen", channelID, userID)
+	if err != nil {
+		return model.Voting{}, err
+	}
+
+	if voting.State != model.StateClosed {
+		err := illegalTransition(voting, model.StateOpen)
+		s.postTransitionRefused(channelID, userID, voting, err, "переоткрыть")
+		return model.Voting{}, err
+	}
+
+	now := time.Now()
+	deadline, err := s.commandDeadline(flags, time.Time{}, now, channelID, userID)
+	if err != nil {
+		return model.Voting{}, err
+	}
+
+	reopened, err := s.transition(voting, model.StateOpen, now, deadline)
+	if err != nil {
+		s.postTransitionRefused(channelID, userID, voting, err, "переоткрыть")
+		return model.Voting{}, err
+	}
+	return reopened, nil
+}
+
+// ArchiveVoting retires a closed, draft or scheduled voting for good.
+func (s *VotingService) ArchiveVoting(request dto.VotingRequest, channelID, userID string) (model.Voting, error) {
+	voting, _, err := s.creatorVoting(request, "archive", channelID, userID)
+	if err != nil {
+		return model.Voting{}, err
+	}
+
+	archived, err := s.transition(voting, model.StateArchived, time.Now(), time.Time{})
+	if err != nil {
+		s.postTransitionRefused(channelID, userID, voting, err, "отправить в архив")
+		return model.Voting{}, err
+	}
+	return archived, nil
+}
+
+// AdvanceVoting is called by the scheduler once a voting is due: it opens a
+// scheduled voting or closes an open one past its deadline.
+func (s *VotingService) AdvanceVoting(votingID string) (model.Voting, error) {
+	voting, err := s.VoteRepo.GetVoting(votingID)
+	if err != nil {
+		return model.Voting{}, err
+	}
+
+	now := time.Now()
+	switch {
+	case voting.State == model.StateScheduled && !now.Before(voting.OpensAt):
+		return s.transition(voting, model.StateOpen, now, voting.Deadline)
+	case voting.IsOpen() && isExpired(voting, now):
+		return s.transition(voting, model.StateClosed, voting.Deadline, voting.Deadline)
+	}
+
+	err = errors.InvalidTransition.Newf("voting is %s, nothing is due", voting.State)
+	err = errors.AddErrorContext(err, votingID, "Voting has nothing due")
+	return model.Voting{}, err
+}
+
+// SchedulePending hands every scheduled opening and deadline to the
+// scheduler. It is called on startup so they survive restarts.
+func (s *VotingService) SchedulePending() error {
+	votings, err := s.VoteRepo.ListPendingVotings()
+	if err != nil {
+		return err
+	}
+	for _, voting := range votings {
+		s.scheduleNext(voting)
+	}
+	s.Logger.Info("Pending votings scheduled", slog.Int("count", len(votings)))
+	return nil
+}
+
+func (s *VotingService) scheduleNext(voting model.Voting) {
+	switch {
+	case voting.State == model.StateScheduled:
+		s.Deadlines.Schedule(voting.ID, voting.OpensAt)
+	case voting.IsOpen() && !voting.Deadline.IsZero():
+		s.Deadlines.Schedule(voting.ID, voting.Deadline)
+	default:
+		s.Deadlines.Cancel(voting.ID)
+	}
+}
+
+// transition moves the voting to the next state if the lifecycle allows it
+// and reschedules whatever is due next.
+func (s *VotingService) transition(voting model.Voting, to model.VotingState, at, deadline time.Time) (model.Voting, error) {
+	if !voting.State.CanTransitionTo(to) {
+		return model.Voting{}, illegalTransition(voting, to)
+	}
+
+	transition := model.StateTransition{From: voting.State, To: to, At: at}
+	// The verdict of a closed voting keeps to the members it closed with.
+	if to == model.StateClosed && voting.Settings.Quorum > 0 {
+		transition.Members, _ = s.channelMemberCount(voting.ChannelID)
+	}
+	updated, err := s.VoteRepo.TransitionVoting(voting.ID, transition, deadline)
+	if err != nil {
+		return model.Voting{}, err
+	}
+	s.scheduleNext(updated)
+	if updated.PostID != "" {
+		s.Posts.Flush(updated.ID)
+	}
+
+	s.Logger.Info("Voting state changed", slog.String("voting_id", voting.ID),
+		slog.String("from", string(transition.From)), slog.String("to", string(to)))
+	return updated, nil
+}
+
+func illegalTransition(voting model.Voting, to model.VotingState) error {
+	err := errors.InvalidTransition.Newf("voting is %s, can't move it to %s", voting.State, to)
+	return errors.AddErrorContext(err, voting.ID, "Voting state does not allow this transition")
+}
+
+// postTransitionRefused explains to the user why the voting could not be
+// moved; action is the Russian infinitive, as in "открыть".
+func (s *VotingService) postTransitionRefused(channelID, userID string, voting model.Voting, err error, action string) {
+	if errors.GetType(err) != errors.InvalidTransition {
+		return
+	}
+	s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Нельзя %s голосование: сейчас оно в состоянии «%s».",
+		action, utils.FormatState(string(voting.State))))
+}
+
+// creatorVoting parses "<id> [flags]" and finds the voting, which only its
+// creator may manage.
+func (s *VotingService) creatorVoting(request dto.VotingRequest, command, channelID, userID string) (model.Voting, map[string]string, error) {
+	flags, ref, err := utils.ParseFlags(request.Text)
+	ref = strings.TrimSpace(ref)
+	if err == nil && (ref == "" || strings.Contains(ref, " ")) {
+		err = fmt.Errorf("нужен ровно один id голосования")
+	}
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Используйте: /poll %s <id голосования>", command))
+		err := errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "message", fmt.Sprintf("wrong question format, should be /poll %s <voting id>", command))
+		return model.Voting{}, nil, err
+	}
+
+	voting, err := s.findVoting(ref)
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Голосование не найдено.")
+		return model.Voting{}, nil, err
+	}
+
+	if voting.CreatorID != userID {
+		s.PostEphemeralMessage(channelID, userID, "Вы не являетесь создателем этого голосования.")
+		err := errors.BadRequest.New(errors.UnavailableResource.Message())
+		err = errors.AddErrorContext(err, "id", "You are not a creator of this voting")
+		return model.Voting{}, nil, err
+	}
+	return voting, flags, nil
+}
+
+// commandDeadline reads --duration or --until, falling back to current.
+func (s *VotingService) commandDeadline(flags map[string]string, current, now time.Time, channelID, userID string) (time.Time, error) {
+	if flags["duration"] == "" && flags["until"] == "" {
+		return current, nil
+	}
+	deadline, err := utils.ParseDeadline(flags["duration"], flags["until"], now)
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Неверный срок голосования: "+err.Error())
+		err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "deadline", "wrong deadline, use --duration 2h or --until \"2026-11-01 18:00\"")
+		return time.Time{}, err
+	}
+	return deadline, nil
+}
+
+// closedVotingMessage tells why a voting does not take votes; closed is
+// used for votings that have already finished.
+func closedVotingMessage(voting model.Voting, closed string) string {
+	switch voting.State {
+	case model.StateDraft:
+		return "Голосование ещё не опубликовано."
+	case model.StateScheduled:
+		return fmt.Sprintf("Голосование откроется %s.", voting.OpensAt.Format("02.01.2006 15:04"))
+	}
+	return closed
+}
+
+func isExpired(voting model.Voting, now time.Time) bool {
+	return !voting.Deadline.IsZero() && !now.Before(voting.Deadline)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/repository"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// newMemoryService is a service on the memory repository. Its messages to
+// Mattermost fail and are only logged.
+func newMemoryService() (*VotingService, repository.VotingRepository) {
+	repo := repository.NewMemoryRepository()
+	return &VotingService{
+		Client:   mattermodel.NewAPIv4Client("http://localhost"),
+		VoteRepo: repo,
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, repo
+}
+
+// saveTestVoting stores a voting of the creator in the state.
+func saveTestVoting(t *testing.T, repo repository.VotingRepository, state model.VotingState, configure ...func(*model.Voting)) model.Voting {
+	t.Helper()
+	voting := model.Voting{
+		ID:        uuid.NewString(),
+		CreatorID: "creator",
+		Question:  "Обед?",
+		ChannelID: "channel",
+		Options:   []string{"Пицца", "Суши"},
+		CreatedAt: time.Now().Truncate(time.Second),
+		Results:   map[int]int{0: 0, 1: 0},
+		State:     state,
+	}
+	for _, configure := range configure {
+		configure(&voting)
+	}
+	saved, err := repo.SaveVoting(voting)
+	if err != nil {
+		t.Fatalf("save voting: %v", err)
+	}
+	return saved
+}
+
+func TestTransitions(t *testing.T) {
+	states := []model.VotingState{model.StateDraft, model.StateScheduled, model.StateOpen, model.StateClosed, model.StateArchived}
+	for _, from := range states {
+		for _, to := range states {
+			t.Run(string(from)+"-"+string(to), func(t *testing.T) {
+				s, repo := newMemoryService()
+				voting := saveTestVoting(t, repo, from)
+
+				moved, err := s.transition(voting, to, time.Now(), time.Time{})
+				stored, _ := repo.GetVoting(voting.ID)
+				if !from.CanTransitionTo(to) {
+					if errors.GetType(err) != errors.InvalidTransition {
+						t.Errorf("got %v, want an invalid transition", err)
+					}
+					if stored.State != from {
+						t.Errorf("stored state is %s, want %s", stored.State, from)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("transition: %v", err)
+				}
+				if moved.State != to || stored.State != to {
+					t.Errorf("got state %s, stored %s, want %s", moved.State, stored.State, to)
+				}
+				last := stored.Transitions[len(stored.Transitions)-1]
+				if last.From != from || last.To != to {
+					t.Errorf("last transition is %s -> %s, want %s -> %s", last.From, last.To, from, to)
+				}
+			})
+		}
+	}
+}
+
+// Of transitions racing from the same state only one wins; the others see
+// the state changed under them.
+func TestTransitionRace(t *testing.T) {
+	s, repo := newMemoryService()
+	voting := saveTestVoting(t, repo, model.StateOpen)
+
+	const racers = 8
+	errs := make(chan error, racers)
+	var wg sync.WaitGroup
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.transition(voting, model.StateClosed, time.Now(), time.Time{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	won := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case errors.GetType(err) != errors.InvalidTransition:
+			t.Errorf("got %v, want an invalid transition", err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d transitions won, want one", won)
+	}
+
+	// The stale copy still says open, the repository knows better.
+	if _, err := s.transition(voting, model.StateClosed, time.Now(), time.Time{}); errors.GetType(err) != errors.InvalidTransition {
+		t.Errorf("transition of a stale copy: got %v, want an invalid transition", err)
+	}
+}
+
+func TestLifecycleCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   model.VotingState
+		command func(s *VotingService, request dto.VotingRequest, userID string) (model.Voting, error)
+		text    string
+		userID  string
+		want    model.VotingState
+		fails   errors.ErrorType
+	}{
+		{name: "open a draft", state: model.StateDraft, command: openCommand, want: model.StateOpen},
+		{name: "open a scheduled voting early", state: model.StateScheduled, command: openCommand, want: model.StateOpen},
+		{name: "open an open voting", state: model.StateOpen, command: openCommand, fails: errors.InvalidTransition},
+		{name: "open a closed voting", state: model.StateClosed, command: openCommand, fails: errors.InvalidTransition},
+		{name: "open with a bad duration", state: model.StateDraft, command: openCommand, text: " --duration soon", fails: errors.BadRequest},
+		{name: "open someone else's voting", state: model.StateDraft, command: openCommand, userID: "stranger", fails: errors.BadRequest},
+		{name: "reopen a closed voting", state: model.StateClosed, command: reopenCommand, want: model.StateOpen},
+		{name: "reopen an open voting", state: model.StateOpen, command: reopenCommand, fails: errors.InvalidTransition},
+		{name: "reopen an archived voting", state: model.StateArchived, command: reopenCommand, fails: errors.InvalidTransition},
+		{name: "archive a closed voting", state: model.StateClosed, command: archiveCommand, want: model.StateArchived},
+		{name: "archive a draft", state: model.StateDraft, command: archiveCommand, want: model.StateArchived},
+		{name: "archive an open voting", state: model.StateOpen, command: archiveCommand, fails: errors.InvalidTransition},
+		{name: "archive an archived voting", state: model.StateArchived, command: archiveCommand, fails: errors.InvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newMemoryService()
+			voting := saveTestVoting(t, repo, tt.state)
+			userID := tt.userID
+			if userID == "" {
+				userID = voting.CreatorID
+			}
+
+			moved, err := tt.command(s, dto.VotingRequest{Text: voting.ID + tt.text}, userID)
+			stored, _ := repo.GetVoting(voting.ID)
+			if tt.fails != errors.NoType {
+				if errors.GetType(err) != tt.fails {
+					t.Errorf("got %v, want an error of type %v", err, tt.fails)
+				}
+				if stored.State != tt.state {
+					t.Errorf("stored state is %s, want %s", stored.State, tt.state)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("command: %v", err)
+			}
+			if moved.State != tt.want || stored.State != tt.want {
+				t.Errorf("got state %s, stored %s, want %s", moved.State, stored.State, tt.want)
+			}
+		})
+	}
+}
+
+func openCommand(s *VotingService, request dto.VotingRequest, userID string) (model.Voting, error) {
+	return s.OpenVoting(request, "channel", userID)
+}
+
+func reopenCommand(s *VotingService, request dto.VotingRequest, userID string) (model.Voting, error) {
+	return s.ReopenVoting(request, "channel", userID)
+}
+
+func archiveCommand(s *VotingService, request dto.VotingRequest, userID string) (model.Voting, error) {
+	return s.ArchiveVoting(request, "channel", userID)
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/model"
+	"testing"
+)
+
+// The listed vote count is the number of voters in every kind of voting,
+// not the choices a multi-choice or ranked ballot adds to the counters.
+func TestListVotingsCountsVoters(t *testing.T) {
+	s, repo := newMemoryService()
+	choice := saveTestVoting(t, repo, model.StateOpen)
+	multi := saveTestVoting(t, repo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings.MaxChoices = 2
+	})
+	ranked := saveTestVoting(t, repo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings.Kind = model.KindRanked
+	})
+	score := saveTestVoting(t, repo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings.Kind = model.KindScore
+	})
+	cast := func(voting model.Voting, userID string, choices ...int) {
+		ballot := model.Ballot{VotingID: voting.ID, UserID: userID, Choices: choices, Weight: 1}
+		if voting.Settings.IsScore() {
+			ballot.Scores = make([]int, len(choices))
+		}
+		if _, _, err := repo.ReplaceBallot(ballot, voting.Settings.CountedChoices()); err != nil {
+			t.Fatalf("cast ballot: %v", err)
+		}
+	}
+	cast(choice, "first", 0)
+	cast(multi, "first", 0, 1)
+	cast(multi, "second", 0, 1)
+	cast(ranked, "first", 1, 0)
+	cast(score, "first", 0, 1)
+
+	list, err := s.ListVotings(dto.VotingRequest{}, "channel", "creator")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	want := map[string]int{choice.ID: 1, multi.ID: 2, ranked.ID: 1, score.ID: 1}
+	for _, item := range list.Votings {
+		if item.VoteCount != want[item.ID] {
+			t.Errorf("%s has %d votes, want %d", item.Question, item.VoteCount, want[item.ID])
+		}
+	}
+	if len(list.Votings) != len(want) {
+		t.Errorf("listed %d votings, want %d", len(list.Votings), len(want))
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/utils"
+	"log/slog"
+	"slices"
+	"time"
+
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// AddReactions puts the numbered reactions of the options on the voting
+// post, so that voters only have to click them.
+func (s *VotingService) AddReactions(voting model.Voting) {
+	for i := range voting.Options {
+		reaction := &mattermodel.Reaction{UserId: s.BotID, PostId: voting.PostID, EmojiName: utils.NumberEmojis[i]}
+		if _, _, err := s.Client.SaveReaction(reaction); err != nil {
+			s.Logger.Error("Failed to add reaction in Mattermost", slog.String("post_id", voting.PostID),
+				slog.String("emoji", reaction.EmojiName), slog.Any("error", err))
+			return
+		}
+	}
+}
+
+// VoteReaction casts or retracts a vote by a reaction added to or removed
+// from a voting post, by the rules of typed votes: in a single-choice voting
+// a new reaction moves the vote, otherwise it adds to it up to the limit.
+// It returns an empty response when the reaction is not a vote or changes
+// nothing.
+func (s *VotingService) VoteReaction(reaction *mattermodel.Reaction, channelID string, added bool) (dto.VoteResponse, error) {
+	option := utils.EmojiOption(reaction.EmojiName)
+	if option < 0 || reaction.UserId == s.BotID {
+		return dto.VoteResponse{}, nil
+	}
+	voting, err := s.VoteRepo.GetVotingByPost(reaction.PostId)
+	if errors.GetType(err) == errors.NotFound {
+		return dto.VoteResponse{}, nil
+	}
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+	if !voting.Settings.Reactions || option >= len(voting.Options) {
+		return dto.VoteResponse{}, nil
+	}
+
+	userID := reaction.UserId
+	if !voting.IsOpen() || isExpired(voting, time.Now()) {
+		// Removals include the reactions the bot takes off itself.
+		if added {
+			s.removeReaction(reaction)
+			s.PostEphemeralMessage(channelID, userID, closedVotingMessage(voting, "Голосование завершено и больше не принимает голоса."))
+		}
+		return dto.VoteResponse{}, nil
+	}
+
+	ballot, err := s.newBallot(voting, channelID, userID)
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+	previous, err := s.VoteRepo.GetBallot(voting.ID, ballot.UserID)
+	if err != nil && errors.GetType(err) != errors.NotFound {
+		return dto.VoteResponse{}, err
+	}
+
+	switch picked := slices.Contains(previous.Choices, option); {
+	case added == picked:
+		// The ballot already agrees, e.g. the bot took off a reaction the
+		// vote has moved away from.
+		return dto.VoteResponse{}, nil
+	case !added:
+		ballot.Choices = slices.DeleteFunc(slices.Clone(previous.Choices), func(choice int) bool { return choice == option })
+		if len(ballot.Choices) == 0 {
+			return s.retractBallot(voting, ballot.UserID, userID)
+		}
+		return s.castBallot(voting, ballot, userID)
+	case !voting.Settings.IsMultipleChoice():
+		ballot.Choices = []int{option}
+		response, err := s.castBallot(voting, ballot, userID)
+		if err != nil {
+			return dto.VoteResponse{}, err
+		}
+		// One vote shows as one reaction.
+		for _, choice := range previous.Choices {
+			s.removeReaction(&mattermodel.Reaction{UserId: userID, PostId: reaction.PostId, EmojiName: utils.NumberEmojis[choice]})
+		}
+		return response, nil
+	}
+
+	if limit := voting.Settings.ChoiceLimit(); len(previous.Choices) >= limit {
+		s.removeReaction(reaction)
+		return dto.VoteResponse{}, s.choiceLimitReached(channelID, userID, limit)
+	}
+	ballot.Choices = append(slices.Clone(previous.Choices), option)
+	slices.Sort(ballot.Choices)
+	return s.castBallot(voting, ballot, userID)
+}
+
+// checkNotReactionVoting refuses votes cast other than by reactions in a
+// reaction voting: the reactions on the post would no longer match them.
+func (s *VotingService) checkNotReactionVoting(voting model.Voting, channelID, userID string) error {
+	if !voting.Settings.Reactions {
+		return nil
+	}
+	s.PostEphemeralMessage(channelID, userID, "В этом голосовании голосуйте реакциями под его сообщением; снятая реакция отзывает голос.")
+	err := errors.BadRequest.New(errors.BadRequest.Message())
+	return errors.AddErrorContext(err, "reactions", "Reaction votings only take votes by reactions")
+}
+
+// removeReaction takes a user's reaction off a post. Mattermost only lets
+// the bot do it with the permission to remove others' reactions.
+func (s *VotingService) removeReaction(reaction *mattermodel.Reaction) {
+	if _, err := s.Client.DeleteReaction(reaction); err != nil {
+		s.Logger.Warn("Failed to remove reaction in Mattermost", slog.String("post_id", reaction.PostId),
+			slog.String("user_id", reaction.UserId), slog.String("emoji", reaction.EmojiName), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"testing"
+)
+
+// Reactions show everyone who picked what, so a reaction voting is public
+// and can't hide its results.
+func TestReactionVotingsArePublic(t *testing.T) {
+	s, _ := newMemoryService()
+
+	voting, err := s.AddNewVoting(dto.VotingRequest{Text: "--reactions Обед? | Пицца | Суши"}, "channel", "creator")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !voting.Settings.Reactions || !voting.Settings.Public {
+		t.Errorf("got settings %+v, want a public reaction voting", voting.Settings)
+	}
+
+	for _, results := range []string{"voted", "creator", "closed"} {
+		_, err := s.AddNewVoting(dto.VotingRequest{Text: "--reactions --results " + results + " Обед? | Пицца | Суши"}, "channel", "creator")
+		if errors.GetType(err) != errors.BadRequest || errors.GetErrorContext(err)["field"] != "reactions" {
+			t.Errorf("--results %s: got %v, want a reactions error", results, err)
+		}
+	}
+}
+
+// Typed votes would leave the reactions on the post out of step with the
+// ballots, so a reaction voting only takes votes by reactions.
+func TestReactionVotingsRefuseTypedVotes(t *testing.T) {
+	s, repo := newMemoryService()
+	voting := saveTestVoting(t, repo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings = model.VotingSettings{Reactions: true, Public: true}
+	})
+
+	_, err := s.AddNewVote(dto.VotingRequest{Text: voting.ID + " 1"}, voting.ChannelID, "user")
+	if errors.GetType(err) != errors.BadRequest || errors.GetErrorContext(err)["field"] != "reactions" {
+		t.Errorf("vote: got %v, want a reactions error", err)
+	}
+	_, err = s.VoteOption(voting.ID, 0, voting.ChannelID, "user")
+	if errors.GetType(err) != errors.BadRequest || errors.GetErrorContext(err)["field"] != "reactions" {
+		t.Errorf("vote button: got %v, want a reactions error", err)
+	}
+	_, err = s.RemoveVote(dto.VotingRequest{Text: voting.ID}, voting.ChannelID, "user")
+	if errors.GetType(err) != errors.BadRequest || errors.GetErrorContext(err)["field"] != "reactions" {
+		t.Errorf("unvote: got %v, want a reactions error", err)
+	}
+	if _, err := repo.GetBallot(voting.ID, "user"); errors.GetType(err) != errors.NotFound {
+		t.Errorf("typed ballot: got %v, want none stored", err)
+	}
+}
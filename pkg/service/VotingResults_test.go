@@ -0,0 +1,45 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"reflect"
+	"testing"
+)
+
+// Score votings can't be weighted, so a ballot's weight, which older
+// ballots or a changed setting could leave other than 1, counts once.
+func TestScoreResultsCountBallotsOnce(t *testing.T) {
+	voting := model.Voting{
+		Options:  []string{"Пицца", "Суши"},
+		Settings: model.VotingSettings{Kind: model.KindScore, MinScore: 0, MaxScore: 3},
+	}
+	ballots := []model.Ballot{
+		{UserID: "heavy", Choices: []int{0, 1}, Scores: []int{3, 0}, Weight: 5},
+		{UserID: "light", Choices: []int{0, 1}, Scores: []int{1, 2}, Weight: 1},
+	}
+
+	got := scoreResults(voting, ballots)
+	want := &dto.ScoreResultsResponse{
+		MinScore: 0,
+		MaxScore: 3,
+		Options: []dto.OptionScores{
+			{Option: "Пицца", Count: 2, Mean: 2, Median: 2, StdDev: 1,
+				Histogram: []dto.ScoreCount{{Score: 0, Count: 0}, {Score: 1, Count: 1}, {Score: 2, Count: 0}, {Score: 3, Count: 1}}},
+			{Option: "Суши", Count: 2, Mean: 1, Median: 1, StdDev: 1,
+				Histogram: []dto.ScoreCount{{Score: 0, Count: 1}, {Score: 1, Count: 0}, {Score: 2, Count: 1}, {Score: 3, Count: 0}}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scoreResults gave %+v, want %+v", got, want)
+	}
+}
+
+func TestScoreVotingsRefuseWeights(t *testing.T) {
+	s, _ := newMemoryService()
+	_, err := s.AddNewVoting(dto.VotingRequest{Text: "--score 1-5 --weights board Обед? | Пицца | Суши"}, "channel", "creator")
+	if errors.GetType(err) != errors.BadRequest || errors.GetErrorContext(err)["field"] != "weights" {
+		t.Errorf("got %v, want a weights error", err)
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/repository"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// A search across channels checks the membership of the channels it finds,
+// once each, which the bot may do, rather than listing the user's channels,
+// which it may not. A channel the user is not in is left out of the search
+// again, so its votings can't use up the postings of a term before the
+// user's match is read.
+func TestSearchAllChannelsFiltersByUserChannels(t *testing.T) {
+	checked := make(map[string]int)
+	mattermost := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/members/user") {
+			checked[r.URL.Path]++
+		}
+		if r.URL.Path != "/api/v4/channels/also-mine/members/user" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"channel_id": "also-mine", "user_id": "user"}`))
+	}))
+	defer mattermost.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo, err := repository.NewSQLRepository(repository.DriverSQLite, filepath.Join(t.TempDir(), "voting.db"), logger)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer repo.Close()
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	s := &VotingService{
+		Client:   mattermodel.NewAPIv4Client(mattermost.URL),
+		VoteRepo: repo,
+		Logger:   logger,
+	}
+
+	term := "z" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	save := func(id, channelID string) {
+		_, err := repo.SaveVoting(model.Voting{
+			ID:        id,
+			CreatorID: "creator",
+			Question:  term,
+			ChannelID: channelID,
+			Options:   []string{"Да", "Нет"},
+			CreatedAt: time.Now(),
+			Results:   map[int]int{0: 0, 1: 0},
+			State:     model.StateOpen,
+		})
+		if err != nil {
+			t.Fatalf("save voting: %v", err)
+		}
+	}
+	// Postings are read in voting ID order, the foreign ones first.
+	for i := 0; i < 1001; i++ {
+		save(fmt.Sprintf("0%07d-%s", i, uuid.NewString()[9:]), "foreign")
+	}
+	match := "f" + uuid.NewString()[1:]
+	save(match, "also-mine")
+
+	response, err := s.SearchVotings(dto.VotingRequest{Text: term + " --channel all"}, "mine", "user")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(response.Votings) != 1 || response.Votings[0].ID != match {
+		t.Errorf("found %d votings, want only %s", len(response.Votings), match)
+	}
+	if len(checked) != 2 || checked["/api/v4/channels/foreign/members/user"] != 1 {
+		t.Errorf("checked memberships %v, want foreign and also-mine once each", checked)
+	}
+}
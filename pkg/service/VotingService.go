@@ -1,12 +1,17 @@
 package service
 
 import (
+	"fmt"
 	"go-voting-bot/pkg/dto"
 	"go-voting-bot/pkg/errors"
 	"go-voting-bot/pkg/model"
 	"go-voting-bot/pkg/repository"
+	"go-voting-bot/pkg/tally"
 	"go-voting-bot/pkg/utils"
 	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,15 +21,65 @@ import (
 type VotingService struct {
 	Client   *mattermodel.Client4
 	VoteRepo repository.VotingRepository
-	Logger   *slog.Logger
+	// BotID is the bot's own user, which adds the reactions of reaction votings.
+	BotID     string
+	Deadlines *DeadlineScheduler
+	// Posts keeps the voting cards in step with the votes.
+	Posts *PostRefresher
+	// BallotSecret keys the voter hashes of anonymous votings.
+	BallotSecret []byte
+	Logger       *slog.Logger
+
+	// replies collects the messages of the slash command the service copy
+	// was made for; see WithReplies.
+	replies *CommandReplies
 }
 
 func (s *VotingService) AddNewVoting(request dto.VotingRequest, channelID, userID string) (model.Voting, error) {
-	parts := strings.Split(request.Text, "|")
+	flags, text, err := utils.ParseFlags(request.Text, "draft", "ranked", "anonymous", "public", "reactions")
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Неверный формат запроса: "+err.Error())
+		err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "message", "wrong create flags format")
+		return model.Voting{}, err
+	}
+
+	now := time.Now()
+	state := model.StateOpen
+	opensAt, err := utils.ParseFutureTime(flags["start"], now)
+	if err == nil && !opensAt.IsZero() {
+		state = model.StateScheduled
+	}
+	if err == nil && flags["draft"] != "" {
+		state = model.StateDraft
+		if !opensAt.IsZero() || flags["duration"] != "" {
+			err = fmt.Errorf("для черновика время начала и длительность указываются при публикации: /poll open <id> --duration 2h")
+		}
+	}
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Неверный срок голосования: "+err.Error())
+		err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "start", "wrong start time, use --start \"2026-11-01 10:00\"")
+		return model.Voting{}, err
+	}
+
+	start := now
+	if !opensAt.IsZero() {
+		start = opensAt
+	}
+	deadline, err := utils.ParseDeadline(flags["duration"], flags["until"], start)
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Неверный срок голосования: "+err.Error())
+		err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "deadline", "wrong deadline, use --duration 2h or --until \"2026-11-01 18:00\"")
+		return model.Voting{}, err
+	}
+
+	parts := strings.Split(text, "|")
 	if len(parts) < 3 {
 		s.Logger.Error("Invalid format: requires question and at least two options", slog.String("text", request.Text))
 		s.PostEphemeralMessage(channelID, userID, "Неверный формат запроса.  Убедитесь, что вы указали вопрос и как минимум два варианта ответа.  Пример: /poll create Вопрос | Вариант 1 | Вариант 2")
-		err := errors.BadRequest.Wrapf(nil, errors.InvalidFormat.Message())
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
 		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll create question | ans 1 | ans 2")
 		return model.Voting{}, err
 	}
@@ -37,69 +92,404 @@ func (s *VotingService) AddNewVoting(request dto.VotingRequest, channelID, userI
 
 	if question == "" || len(options) < 2 {
 		s.PostEphemeralMessage(channelID, userID, "Необходимо указать вопрос и как минимум два варианта ответа.")
-		err := errors.BadRequest.Wrapf(nil, errors.InvalidFormat.Message())
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
 		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll create question | ans 1 | ans 2 ...")
 		return model.Voting{}, err
 	}
 
-	voting := model.Voting{
-		ID:        utils.GenerateVotingID(),
+	var settings model.VotingSettings
+	switch flags["tally"] {
+	case "", "irv":
+	case "schulze":
+		settings.Tally = model.TallySchulze
+	default:
+		s.PostEphemeralMessage(channelID, userID, "Неверный способ подсчёта: используйте --tally irv или --tally schulze.")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "tally", "should be irv or schulze")
+		return model.Voting{}, err
+	}
+	// Both tally methods count rankings, so --tally alone makes a ranked voting.
+	if flags["ranked"] != "" || flags["tally"] != "" {
+		if flags["max-choices"] != "" {
+			s.PostEphemeralMessage(channelID, userID, "В рейтинговом голосовании можно расставить все варианты, --max-choices не нужен.")
+			err := errors.BadRequest.New(errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "max-choices", "not allowed with --ranked")
+			return model.Voting{}, err
+		}
+		settings.Kind = model.KindRanked
+	}
+	if value := flags["score"]; value != "" {
+		if settings.IsRanked() || flags["max-choices"] != "" {
+			s.PostEphemeralMessage(channelID, userID, "Голосование с оценками нельзя совмещать с --ranked, --tally и --max-choices.")
+			err := errors.BadRequest.New(errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "score", "not allowed with --ranked, --tally or --max-choices")
+			return model.Voting{}, err
+		}
+		settings.MinScore, settings.MaxScore, err = utils.ParseScoreRange(value)
+		if err != nil {
+			s.PostEphemeralMessage(channelID, userID, "Неверный формат запроса: "+err.Error())
+			err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "score", "wrong score range, use --score 1-5")
+			return model.Voting{}, err
+		}
+		settings.Kind = model.KindScore
+	}
+	if table := flags["weights"]; table != "" {
+		if settings.Kind != model.KindChoice {
+			s.PostEphemeralMessage(channelID, userID, "Веса голосов поддерживаются только в обычных голосованиях, без --ranked, --tally и --score.")
+			err := errors.BadRequest.New(errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "weights", "only allowed in choice votings")
+			return model.Voting{}, err
+		}
+		if _, err := s.weightTable(channelID, userID, table); err != nil {
+			return model.Voting{}, err
+		}
+		settings.Weights = table
+	}
+	if value := flags["max-choices"]; value != "" {
+		maxChoices, err := utils.ParseInt(value)
+		if err == nil && (maxChoices < 1 || maxChoices > len(options)) {
+			err = fmt.Errorf("--max-choices вне диапазона от 1 до %d", len(options))
+		}
+		if err != nil {
+			s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("--max-choices должно быть числом от 1 до %d.", len(options)))
+			err := errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "max-choices", "should be between 1 and the number of options")
+			return model.Voting{}, err
+		}
+		settings.MaxChoices = maxChoices
+	}
+	if flags["anonymous"] != "" {
+		if len(s.BallotSecret) == 0 {
+			s.PostEphemeralMessage(channelID, userID, "Анонимные голосования не настроены: администратору бота нужно задать BALLOT_SECRET.")
+			err := errors.BadRequest.New(errors.UnavailableResource.Message())
+			err = errors.AddErrorContext(err, "anonymous", "BALLOT_SECRET is not set")
+			return model.Voting{}, err
+		}
+		// A distinctive weight would single the voter out.
+		if settings.Weights != "" {
+			s.PostEphemeralMessage(channelID, userID, "Анонимное голосование нельзя совмещать с весами голосов.")
+			err := errors.BadRequest.New(errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "anonymous", "not allowed with --weights")
+			return model.Voting{}, err
+		}
+		settings.Anonymous = true
+	}
+	visibility, ok := resultsVisibilities[flags["results"]]
+	if !ok {
+		s.PostEphemeralMessage(channelID, userID, "Неверная видимость результатов: используйте --results always, voted, creator или closed.")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "results", "should be always, voted, creator or closed")
+		return model.Voting{}, err
+	}
+	settings.Results = visibility
+	if flags["public"] != "" {
+		if settings.Anonymous {
+			s.PostEphemeralMessage(channelID, userID, "Голосование не может быть одновременно анонимным и публичным.")
+			err := errors.BadRequest.New(errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "public", "not allowed with --anonymous")
+			return model.Voting{}, err
+		}
+		settings.Public = true
+	}
+	if flags["reactions"] != "" {
+		// Reactions show who picked what to everyone.
+		if settings.Kind != model.KindChoice || settings.Anonymous || len(options) > len(utils.NumberEmojis) {
+			s.PostEphemeralMessage(channelID, userID, fmt.Sprintf(
+				"Голосовать реакциями можно только в обычных неанонимных голосованиях, где не больше %d вариантов.", len(utils.NumberEmojis)))
+			err := errors.BadRequest.New(errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "reactions", "only allowed in choice votings that are not anonymous, up to ten options")
+			return model.Voting{}, err
+		}
+		// Reaction counts are on the post, so results can't be hidden.
+		if settings.Results != model.ResultsAlways {
+			s.PostEphemeralMessage(channelID, userID, "Реакции видны всем, поэтому с --reactions результаты нельзя скрыть через --results.")
+			err := errors.BadRequest.New(errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "reactions", "only allowed with --results always")
+			return model.Voting{}, err
+		}
+		settings.Reactions = true
+		settings.Public = true
+	}
+	if value := flags["quorum"]; value != "" {
+		settings.Quorum, err = utils.ParseShare(value)
+		if err != nil {
+			s.PostEphemeralMessage(channelID, userID, "Неверный кворум: "+err.Error())
+			err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "quorum", "wrong quorum, use --quorum 60%")
+			return model.Voting{}, err
+		}
+	}
+	if value := flags["threshold"]; value != "" {
+		if settings.Kind != model.KindChoice {
+			s.PostEphemeralMessage(channelID, userID, "Порог принятия поддерживается только в обычных голосованиях, без --ranked, --tally и --score.")
+			err := errors.BadRequest.New(errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "threshold", "only allowed in choice votings")
+			return model.Voting{}, err
+		}
+		settings.Threshold, err = utils.ParseShare(value)
+		if err != nil {
+			s.PostEphemeralMessage(channelID, userID, "Неверный порог принятия: "+err.Error())
+			err = errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+			err = errors.AddErrorContext(err, "threshold", "wrong threshold, use --threshold 2/3")
+			return model.Voting{}, err
+		}
+	}
+
+	return s.saveNewVoting(model.Voting{
 		CreatorID: userID,
 		ChannelID: channelID,
 		Question:  question,
 		Options:   options,
-		CreatedAt: time.Now(),
-		Results:   make(map[int]int),
-		IsActive:  true,
+		CreatedAt: now,
+		State:     state,
+		Deadline:  deadline,
+		OpensAt:   opensAt,
+		Settings:  settings,
+	})
+}
+
+// resultsVisibilities maps the values of --results, and of the dialog
+// field, to visibilities; empty is the default.
+var resultsVisibilities = map[string]model.ResultsVisibility{
+	"":        model.ResultsAlways,
+	"always":  model.ResultsAlways,
+	"voted":   model.ResultsVoted,
+	"creator": model.ResultsCreator,
+	"closed":  model.ResultsClosed,
+}
+
+// saveNewVoting gives a new voting its ID, empty results and first state
+// transition, stores it and schedules its next transition.
+func (s *VotingService) saveNewVoting(voting model.Voting) (model.Voting, error) {
+	voting.ID = utils.GenerateVotingID()
+	voting.Results = make(map[int]int)
+	voting.Transitions = []model.StateTransition{{To: voting.State, At: voting.CreatedAt}}
+	voting, err := s.VoteRepo.SaveVoting(voting)
+	if err != nil {
+		return model.Voting{}, err
 	}
-	return s.VoteRepo.SaveVoting(voting)
+	s.scheduleNext(voting)
+	return voting, nil
 }
 
-func (s *VotingService) AddNewVote(request dto.VotingRequest, channelID, userID string) (model.Voting, error) {
-	parts := strings.Split(request.Text, " ")
+func (s *VotingService) AddNewVote(request dto.VotingRequest, channelID, userID string) (dto.VoteResponse, error) {
+	parts := strings.SplitN(strings.TrimSpace(request.Text), " ", 2)
 	if len(parts) != 2 {
-		s.Logger.Error("Invalid format: requires voting id and 1 answer", slog.String("text", request.Text))
-		s.PostEphemeralMessage(channelID, userID, "Неверный формат запроса.  Убедитесь, что вы указали /poll vote <id голосования> <номер варианта>")
-		err := errors.BadRequest.Wrapf(nil, errors.InvalidFormat.Message())
-		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll <vote voting id> <answer variant> ")
-		return model.Voting{}, err
+		s.Logger.Error("Invalid format: requires voting id and answers", slog.String("text", request.Text))
+		s.PostEphemeralMessage(channelID, userID, "Неверный формат запроса.  Убедитесь, что вы указали /poll vote <id голосования> <номер варианта>[,<номер варианта>...] или, в рейтинговом голосовании, <номер>><номер>...")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll vote <voting id> <answer variant>[,<answer variant>...]")
+		return dto.VoteResponse{}, err
 	}
 
-	votingID := strings.TrimSpace(parts[0])
-	optionNumberStr := strings.TrimSpace(parts[1])
+	voting, err := s.openVoting(strings.TrimSpace(parts[0]), channelID, userID, "Голосование завершено и больше не принимает голоса.")
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+	if err := s.checkNotReactionVoting(voting, channelID, userID); err != nil {
+		return dto.VoteResponse{}, err
+	}
+	ballot, err := s.newBallot(voting, channelID, userID)
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+	if voting.Settings.IsScore() {
+		ballot.Choices, ballot.Scores, err = s.parseScores(voting, parts[1], channelID, userID)
+	} else {
+		ballot.Choices, err = s.parseChoices(voting, parts[1], channelID, userID)
+	}
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+	return s.castBallot(voting, ballot, userID)
+}
 
-	optionNumber, err := utils.ParseInt(optionNumberStr)
+// VoteOption casts a vote for one option, as a button on the voting post
+// does. In a multiple-choice voting it toggles the option in the user's
+// ballot instead, and unpicking the last option retracts the vote.
+func (s *VotingService) VoteOption(votingID string, option int, channelID, userID string) (dto.VoteResponse, error) {
+	voting, err := s.openVoting(votingID, channelID, userID, "Голосование завершено и больше не принимает голоса.")
 	if err != nil {
-		s.PostEphemeralMessage(channelID, userID, "Номер варианта должен быть числом.")
-		err = errors.BadRequest.Wrapf(err, errors.WrongType.Message())
-		err = errors.AddErrorContext(err, "id", "wrong id format, should be an integer")
-		return model.Voting{}, err
+		return dto.VoteResponse{}, err
+	}
+	if err := s.checkNotReactionVoting(voting, channelID, userID); err != nil {
+		return dto.VoteResponse{}, err
+	}
+	if voting.Settings.Kind != model.KindChoice || option < 0 || option >= len(voting.Options) {
+		s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("В этом голосовании голосуйте командой `/poll vote %s`.", voting.ID))
+		err := errors.BadRequest.New(errors.BadRequest.Message())
+		err = errors.AddErrorContext(err, "option", "Option can't be voted for with a button")
+		return dto.VoteResponse{}, err
 	}
 
-	voting, err := s.VoteRepo.GetVoting(votingID)
+	ballot, err := s.newBallot(voting, channelID, userID)
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+	ballot.Choices = []int{option}
+	if !voting.Settings.IsMultipleChoice() {
+		return s.castBallot(voting, ballot, userID)
+	}
+
+	previous, err := s.VoteRepo.GetBallot(voting.ID, ballot.UserID)
+	switch {
+	case errors.GetType(err) == errors.NotFound:
+		return s.castBallot(voting, ballot, userID)
+	case err != nil:
+		return dto.VoteResponse{}, err
+	}
+	if slices.Contains(previous.Choices, option) {
+		ballot.Choices = slices.DeleteFunc(slices.Clone(previous.Choices), func(choice int) bool { return choice == option })
+		if len(ballot.Choices) == 0 {
+			return s.retractBallot(voting, ballot.UserID, userID)
+		}
+		return s.castBallot(voting, ballot, userID)
+	}
+	if limit := voting.Settings.ChoiceLimit(); len(previous.Choices) >= limit {
+		return dto.VoteResponse{}, s.choiceLimitReached(channelID, userID, limit)
+	}
+	ballot.Choices = append(slices.Clone(previous.Choices), option)
+	slices.Sort(ballot.Choices)
+	return s.castBallot(voting, ballot, userID)
+}
+
+// choiceLimitReached tells the user that another option can't be picked
+// until one is unpicked.
+func (s *VotingService) choiceLimitReached(channelID, userID string, limit int) error {
+	s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("В этом голосовании можно выбрать не больше %d %s. Сначала снимите выбор с другого варианта.",
+		limit, utils.Plural(limit, "варианта", "вариантов", "вариантов")))
+	err := errors.BadRequest.New(errors.BadRequest.Message())
+	return errors.AddErrorContext(err, "option", "Too many answer variants")
+}
+
+func (s *VotingService) RemoveVote(request dto.VotingRequest, channelID, userID string) (dto.VoteResponse, error) {
+	votingID := strings.TrimSpace(request.Text)
+	if votingID == "" {
+		s.Logger.Error("Wrong question format, should be /poll unvote <voting id>", slog.String("text", request.Text))
+		s.PostEphemeralMessage(channelID, userID, "Используйте: /poll unvote <id голосования>")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll unvote <voting id>")
+		return dto.VoteResponse{}, err
+	}
+
+	voting, err := s.openVoting(votingID, channelID, userID, "Голосование завершено, голос уже нельзя отозвать.")
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+	if err := s.checkNotReactionVoting(voting, channelID, userID); err != nil {
+		return dto.VoteResponse{}, err
+	}
+	voterKey, err := s.voterKey(voting, channelID, userID)
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+	response, err := s.retractBallot(voting, voterKey, userID)
+	if errors.GetType(err) == errors.NotFound {
+		s.PostEphemeralMessage(channelID, userID, "Вы ещё не голосовали в этом голосовании.")
+	}
+	return response, err
+}
+
+// openVoting finds a voting that takes votes; closed tells the user why a
+// closed one does not. Votes are only taken in the voting's channel, whose
+// members its quorum counts.
+func (s *VotingService) openVoting(votingID, channelID, userID, closed string) (model.Voting, error) {
+	voting, err := s.findVoting(votingID)
 	if err != nil {
 		s.Logger.Error("Error getting voting from Tarantool" + err.Error())
 		s.PostEphemeralMessage(channelID, userID, "Голосование не найдено.")
 		return model.Voting{}, err
 	}
-
-	if !voting.IsActive {
-		s.PostEphemeralMessage(channelID, userID, "Голосование завершено и больше не принимает голоса.")
-		err = errors.BadRequest.Wrapf(err, errors.UnavailableResource.Message())
+	if err := checkVotingChannel(voting, channelID); err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Это голосование создано в другом канале, голосуйте там.")
+		return model.Voting{}, err
+	}
+	if !voting.IsOpen() || isExpired(voting, time.Now()) {
+		s.PostEphemeralMessage(channelID, userID, closedVotingMessage(voting, closed))
+		err := errors.BadRequest.New(errors.UnavailableResource.Message())
 		err = errors.AddErrorContext(err, "id", "Voting is finished")
 		return model.Voting{}, err
 	}
+	return voting, nil
+}
 
-	if optionNumber < 1 || optionNumber > len(voting.Options) {
-		s.PostEphemeralMessage(channelID, userID, "Неверный номер варианта.")
-		err = errors.BadRequest.Wrapf(err, errors.BadRequest.Message())
-		err = errors.AddErrorContext(err, "answer", "Wrong answer variant")
-		return model.Voting{}, err
+// newBallot starts the user's ballot in the voting, without choices yet.
+func (s *VotingService) newBallot(voting model.Voting, channelID, userID string) (model.Ballot, error) {
+	voterKey, err := s.voterKey(voting, channelID, userID)
+	if err != nil {
+		return model.Ballot{}, err
+	}
+	weight, err := s.ballotWeight(voting, userID)
+	if err != nil {
+		return model.Ballot{}, err
+	}
+	ballot := model.Ballot{
+		VotingID: voting.ID,
+		UserID:   voterKey,
+		Weight:   weight,
+	}
+	if !voting.Settings.Anonymous {
+		ballot.CastAt = time.Now()
+	}
+	return ballot, nil
+}
+
+// castBallot stores the ballot in place of the user's previous one; the
+// repository moves the result counters by the difference between them in
+// the same step.
+func (s *VotingService) castBallot(voting model.Voting, ballot model.Ballot, userID string) (dto.VoteResponse, error) {
+	votingID := voting.ID
+	response := dto.VoteResponse{
+		VotingID: votingID,
+		Question: voting.Question,
+		Option:   ballotLabels(voting, ballot),
+		Public:   voting.Settings.Public,
+	}
+
+	previous, replaced, err := s.VoteRepo.ReplaceBallot(ballot, voting.Settings.CountedChoices())
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+
+	if replaced {
+		response.PreviousOption = ballotLabels(voting, previous)
+		if slices.Equal(previous.Choices, ballot.Choices) && slices.Equal(previous.Scores, ballot.Scores) {
+			return response, nil
+		}
 	}
 
-	voting.Results[optionNumber-1]++
-	s.Logger.Info("Vote registered", slog.String("voting_id", votingID), slog.Int("option_number", optionNumber), slog.String("user_id", userID))
-	return s.VoteRepo.SaveVoting(voting)
+	s.Posts.Touch(votingID)
+
+	if voting.Settings.Anonymous {
+		s.Logger.Info("Anonymous vote registered", slog.String("voting_id", votingID))
+		return response, nil
+	}
+	s.Logger.Info("Vote registered", slog.String("voting_id", votingID), slog.Any("options", ballot.Choices),
+		slog.Float64("weight", ballot.Weight), slog.String("user_id", userID))
+	return response, nil
+}
+
+// retractBallot deletes the ballot stored under voterKey; the repository
+// takes it off the result counters in the same step.
+func (s *VotingService) retractBallot(voting model.Voting, voterKey, userID string) (dto.VoteResponse, error) {
+	previous, err := s.VoteRepo.DeleteBallot(voting.ID, voterKey, voting.Settings.CountedChoices())
+	if err != nil {
+		return dto.VoteResponse{}, err
+	}
+
+	s.Posts.Touch(voting.ID)
+
+	if voting.Settings.Anonymous {
+		s.Logger.Info("Anonymous vote retracted", slog.String("voting_id", voting.ID))
+	} else {
+		s.Logger.Info("Vote retracted", slog.String("voting_id", voting.ID), slog.String("user_id", userID))
+	}
+	return dto.VoteResponse{
+		VotingID:       voting.ID,
+		Question:       voting.Question,
+		PreviousOption: ballotLabels(voting, previous),
+	}, nil
 }
 
 func (s *VotingService) GetResultsByVotingId(request dto.VotingRequest, channelID string, userID string) (dto.VotingResultsResponse, string, error) {
@@ -107,24 +497,90 @@ func (s *VotingService) GetResultsByVotingId(request dto.VotingRequest, channelI
 	if votingID == "" {
 		s.Logger.Error("Wrong question format, should be /poll results <voting id>", slog.String("text", request.Text))
 		s.PostEphemeralMessage(channelID, userID, "Используйте: /poll results <id голосования>")
-		err := errors.BadRequest.Wrapf(nil, errors.InvalidFormat.Message())
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
 		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll results <voting id>")
 		return dto.VotingResultsResponse{}, "", err
 	}
-	voting, err := s.VoteRepo.GetVoting(votingID)
+	voting, err := s.findVoting(votingID)
 	if err != nil {
 		return dto.VotingResultsResponse{}, "", err
 	}
+	if err := checkVotingChannel(voting, channelID); err != nil {
+		return dto.VotingResultsResponse{}, voting.ID, err
+	}
+	if err := s.checkResultsAccess(voting, channelID, userID); err != nil {
+		return dto.VotingResultsResponse{}, voting.ID, err
+	}
+	results, err := s.VotingResults(voting)
+	results.Private = !voting.ResultsPublic()
+	return results, voting.ID, err
+}
+
+// ForeignChannelField is the error context field of the Forbidden error
+// for a voting asked about from a channel other than its own.
+const ForeignChannelField = "channel"
+
+// checkVotingChannel refuses with a Forbidden error when the voting was
+// created in another channel, so that its results and voters are not
+// shown to people outside of it and they can't vote in it.
+func checkVotingChannel(voting model.Voting, channelID string) error {
+	if voting.ChannelID == channelID {
+		return nil
+	}
+	err := errors.Forbidden.Newf("voting %s belongs to another channel", voting.ID)
+	return errors.AddErrorContext(err, ForeignChannelField, "Voting belongs to another channel")
+}
+
+// checkResultsAccess refuses with a Forbidden error when the results
+// visibility of the voting hides its results from the user. The error
+// context field holds the visibility.
+func (s *VotingService) checkResultsAccess(voting model.Voting, channelID, userID string) error {
+	if voting.ResultsPublic() {
+		return nil
+	}
+	voted := false
+	if voting.Settings.Results == model.ResultsVoted {
+		voterKey, err := s.voterKey(voting, channelID, userID)
+		if err != nil {
+			return err
+		}
+		_, err = s.VoteRepo.GetBallot(voting.ID, voterKey)
+		switch {
+		case err == nil:
+			voted = true
+		case errors.GetType(err) != errors.NotFound:
+			return err
+		}
+	}
+	if voting.ResultsVisibleTo(userID, voted) {
+		return nil
+	}
+	err := errors.Forbidden.Newf("results of voting %s are hidden", voting.ID)
+	return errors.AddErrorContext(err, string(voting.Settings.Results), "Results are hidden by the voting's results visibility")
+}
+
+// VotingResults tallies the stored counters of a voting. Percentages are
+// shares of voters, so with multiple choice they may add up to more than 100.
+// A ranked voting is also counted from its ballots by its tally method, and
+// a voting with a quorum or a threshold gets a verdict.
+func (s *VotingService) VotingResults(voting model.Voting) (dto.VotingResultsResponse, error) {
+	ballots, err := s.VoteRepo.GetBallots(voting.ID)
+	if err != nil {
+		return dto.VotingResultsResponse{}, err
+	}
+	totalVoters := len(ballots)
 
 	totalVotes := 0
+	for _, votes := range voting.Results {
+		totalVotes += votes
+	}
 	results := make([]dto.Result, len(voting.Options))
 
 	for i, option := range voting.Options {
 		votes := voting.Results[i]
-		totalVotes += votes
 		percentage := 0.0
-		if totalVotes > 0 {
-			percentage = float64(votes) / float64(totalVotes) * 100
+		if totalVoters > 0 {
+			percentage = float64(votes) / float64(totalVoters) * 100
 		}
 		results[i] = dto.Result{
 			Option:     option,
@@ -133,75 +589,474 @@ func (s *VotingService) GetResultsByVotingId(request dto.VotingRequest, channelI
 		}
 	}
 
-	return dto.VotingResultsResponse{
-		Question:   voting.Question,
-		Options:    voting.Options,
-		Results:    results,
-		TotalVotes: totalVotes,
-	}, votingID, nil
+	response := dto.VotingResultsResponse{
+		Question:    voting.Question,
+		Options:     voting.Options,
+		Results:     results,
+		TotalVotes:  totalVotes,
+		TotalVoters: totalVoters,
+		MaxChoices:  voting.Settings.ChoiceLimit(),
+	}
+	if voting.Settings.Weights != "" {
+		weighResults(&response, ballots)
+		response.Weights = voting.Settings.Weights
+	}
+	if voting.Settings.IsScore() {
+		response.Results = nil
+		response.MaxChoices = len(voting.Options)
+		response.Scores = scoreResults(voting, ballots)
+	}
+	if voting.Settings.IsRanked() {
+		response.MaxChoices = len(voting.Options)
+		rankings := make([][]int, len(ballots))
+		for i, ballot := range ballots {
+			rankings[i] = ballot.Choices
+		}
+		if voting.Settings.Tally == model.TallySchulze {
+			response.Schulze = schulzeResults(voting, rankings)
+		} else {
+			response.Runoff = runoffResults(voting, rankings)
+		}
+	}
+	if voting.Settings.HasVerdict() {
+		response.Verdict = s.verdict(voting, response)
+	}
+	return response, nil
+}
+
+// weighResults adds the weighted totals of the ballots to the results.
+func weighResults(response *dto.VotingResultsResponse, ballots []model.Ballot) {
+	for _, ballot := range ballots {
+		response.TotalWeight += ballot.Weight
+		for _, option := range ballot.Choices {
+			if option >= 0 && option < len(response.Results) {
+				response.Results[option].WeightedVotes += ballot.Weight
+			}
+		}
+	}
+	if response.TotalWeight == 0 {
+		return
+	}
+	for i := range response.Results {
+		response.Results[i].WeightedPercentage = response.Results[i].WeightedVotes / response.TotalWeight * 100
+	}
+}
+
+func runoffResults(voting model.Voting, rankings [][]int) *dto.RunoffResults {
+	runoff := tally.IRV(len(voting.Options), rankings)
+
+	counts := func(votes map[int]int) []dto.RunoffCount {
+		options := slices.Sorted(maps.Keys(votes))
+		result := make([]dto.RunoffCount, len(options))
+		for i, option := range options {
+			result[i] = dto.RunoffCount{Option: optionLabel(voting, option), Votes: votes[option]}
+		}
+		return result
+	}
+
+	results := &dto.RunoffResults{Winners: optionLabels(voting, runoff.Winners)}
+	for _, round := range runoff.Rounds {
+		results.Rounds = append(results.Rounds, dto.RunoffRound{
+			Votes:      counts(round.Votes),
+			Eliminated: optionLabels(voting, round.Eliminated),
+			Transfers:  counts(round.Transfers),
+			Exhausted:  round.Exhausted,
+		})
+	}
+	return results
 }
 
-func (s *VotingService) EndVotingByVotingId(request dto.VotingRequest, channelID, userID string) (string, error) {
+func (s *VotingService) EndVotingByVotingId(request dto.VotingRequest, channelID, userID string) (model.Voting, error) {
 	votingID := strings.TrimSpace(request.Text)
 	if votingID == "" {
 		s.Logger.Error("Wrong question format, should be /poll end <voting id>", slog.String("text", request.Text))
 		s.PostEphemeralMessage(channelID, userID, "Использование: /poll end <id голосования>")
-		err := errors.BadRequest.Wrapf(nil, errors.InvalidFormat.Message())
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
 		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll end <voting id>")
-		return "", err
+		return model.Voting{}, err
 	}
 
-	voting, err := s.VoteRepo.GetVoting(votingID)
+	voting, err := s.findVoting(votingID)
 	if err != nil {
 		s.Logger.Error("Error getting voting from Tarantool" + err.Error())
 		s.PostEphemeralMessage(channelID, userID, "Голосование не найдено.")
-		return "", nil
+		return model.Voting{}, err
 	}
+	votingID = voting.ID
 
 	if voting.CreatorID != userID {
 		s.PostEphemeralMessage(channelID, userID, "Вы не являетесь создателем этого голосования.")
-		err := errors.BadRequest.Wrapf(nil, errors.UnavailableResource.Message())
+		err := errors.BadRequest.New(errors.UnavailableResource.Message())
 		err = errors.AddErrorContext(err, "id", "You are not a creator of this voting")
-		return "", err
+		return model.Voting{}, err
 	}
 
-	voting.IsActive = false
-	voting.ClosedAt = time.Now()
-	_, err = s.VoteRepo.SaveVoting(voting)
+	closed, err := s.transition(voting, model.StateClosed, time.Now(), voting.Deadline)
+	if err != nil {
+		s.postTransitionRefused(channelID, userID, voting, err, "завершить")
+		return model.Voting{}, err
+	}
 
 	s.Logger.Info("Voting ended", slog.String("voting_id", votingID), slog.String("user_id", userID))
-	return votingID, err
+	return closed, nil
 }
 
 func (s *VotingService) DeleteVotingByVotingId(request dto.VotingRequest, channelID, userID string) (string, error) {
 	votingID := strings.TrimSpace(request.Text)
 	if votingID == "" {
 		s.Logger.Error("Wrong question format, should be /poll delete <voting id>", slog.String("text", request.Text))
-		err := errors.BadRequest.Wrapf(nil, errors.InvalidFormat.Message())
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
 		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll delete <voting id>")
 		s.PostEphemeralMessage(channelID, userID, "Используйте: /poll delete <id голосования>")
 		return "", err
 	}
 
-	voting, err := s.VoteRepo.GetVoting(votingID)
+	voting, err := s.findVoting(votingID)
 	if err != nil {
 		s.Logger.Error("Error getting voting from Tarantool" + err.Error())
 		s.PostEphemeralMessage(channelID, userID, "Голосование не найдено.")
 		return "", err
 	}
+	votingID = voting.ID
 
 	if voting.CreatorID != userID {
 		s.PostEphemeralMessage(channelID, userID, "Вы не являетесь создателем этого голосования.")
-		err := errors.BadRequest.Wrapf(nil, errors.UnavailableResource.Message())
+		err := errors.BadRequest.New(errors.UnavailableResource.Message())
 		err = errors.AddErrorContext(err, "id", "You are not a creator of this voting")
 		return "", err
 	}
 	s.Logger.Info("Voting deleted", slog.String("voting_id", votingID), slog.String("user_id", userID))
+	s.Deadlines.Cancel(votingID)
 
 	return s.VoteRepo.DeleteVoting(votingID)
 }
 
+const votingListPageSize = 10
+
+func (s *VotingService) ListVotings(request dto.VotingRequest, channelID, userID string) (dto.VotingListResponse, error) {
+	state, statusName, page := model.VotingState(""), "all", 1
+	for _, arg := range strings.Fields(request.Text) {
+		arg = strings.ToLower(arg)
+		switch {
+		case arg == "all":
+			state, statusName = "", arg
+		case arg == "active":
+			state, statusName = model.StateOpen, arg
+		case model.VotingState(arg).IsValid():
+			state, statusName = model.VotingState(arg), arg
+		default:
+			number, err := utils.ParseInt(arg)
+			if err == nil && number < 1 {
+				err = fmt.Errorf("номер страницы должен быть больше нуля")
+			}
+			if err != nil {
+				s.PostEphemeralMessage(channelID, userID, "Используйте: /poll list [active|draft|scheduled|closed|archived|all] [номер страницы]")
+				err := errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+				err = errors.AddErrorContext(err, "message", "wrong list format, should be /poll list [state|all] [page]")
+				return dto.VotingListResponse{}, err
+			}
+			page = number
+		}
+	}
+
+	votings, err := s.VoteRepo.ListVotingsByChannel(channelID, state, (page-1)*votingListPageSize, votingListPageSize+1)
+	if err != nil {
+		return dto.VotingListResponse{}, err
+	}
+
+	response := dto.VotingListResponse{
+		Status:  statusName,
+		Page:    page,
+		HasMore: len(votings) > votingListPageSize,
+	}
+	if response.HasMore {
+		votings = votings[:votingListPageSize]
+	}
+
+	creatorIDs := make([]string, 0, len(votings))
+	for _, voting := range votings {
+		creatorIDs = append(creatorIDs, voting.CreatorID)
+	}
+	usernames := s.usernames(creatorIDs)
+
+	// Counters add up choices, several per voter in multi-choice and ranked
+	// votings and none in score ones, so the ballots are counted instead.
+	votingIDs := make([]string, len(votings))
+	for i, voting := range votings {
+		votingIDs[i] = voting.ID
+	}
+	voterCounts := map[string]int{}
+	if len(votingIDs) > 0 {
+		voterCounts, err = s.VoteRepo.CountBallots(votingIDs)
+		if err != nil {
+			return dto.VotingListResponse{}, err
+		}
+	}
+
+	for _, voting := range votings {
+		response.Votings = append(response.Votings, dto.VotingListItem{
+			ID:        voting.ID,
+			ShortID:   utils.ShortID(voting.ID),
+			Question:  voting.Question,
+			Creator:   usernames[voting.CreatorID],
+			State:     string(voting.State),
+			VoteCount: voterCounts[voting.ID],
+			CreatedAt: voting.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+const searchResultsLimit = 10
+
+func (s *VotingService) SearchVotings(request dto.VotingRequest, channelID, userID string) (dto.VotingSearchResponse, error) {
+	flags, query, err := utils.ParseFlags(request.Text, "closed")
+	scope := flags["channel"]
+	if scope == "" {
+		scope = "here"
+	}
+	terms := utils.Tokenize(query)
+	if err == nil && len(terms) == 0 {
+		err = fmt.Errorf("не указан текст для поиска")
+	}
+	if err == nil && scope != "here" && scope != "all" {
+		err = fmt.Errorf("--channel должно быть here или all")
+	}
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Используйте: /poll search <текст> [--channel here|all] [--closed]")
+		err := errors.BadRequest.Wrapf(err, errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "message", "wrong search format, should be /poll search <text> [--channel here|all] [--closed]")
+		return dto.VotingSearchResponse{}, err
+	}
+
+	search := repository.VotingSearch{
+		Terms:         terms,
+		IncludeClosed: flags["closed"] != "",
+		Limit:         searchResultsLimit,
+	}
+	var votings []model.Voting
+	if scope == "here" {
+		search.ChannelIDs = []string{channelID}
+		votings, err = s.VoteRepo.SearchVotings(search)
+	} else {
+		votings, err = s.searchMemberVotings(search, channelID, userID)
+	}
+	if err != nil {
+		return dto.VotingSearchResponse{}, err
+	}
+
+	response := dto.VotingSearchResponse{Query: query}
+	channels := make(map[string]*mattermodel.Channel)
+	teamNames := make(map[string]string)
+	for _, voting := range votings {
+		channel, ok := channels[voting.ChannelID]
+		if !ok {
+			channel = s.channel(voting.ChannelID)
+			channels[voting.ChannelID] = channel
+		}
+		item := dto.VotingSearchItem{
+			ID:        voting.ID,
+			ShortID:   utils.ShortID(voting.ID),
+			Question:  voting.Question,
+			ChannelID: voting.ChannelID,
+			State:     string(voting.State),
+			CreatedAt: voting.CreatedAt,
+			PostID:    voting.PostID,
+		}
+		if channel != nil {
+			item.ChannelName = channel.Name
+			if _, ok := teamNames[channel.TeamId]; !ok && channel.TeamId != "" {
+				teamNames[channel.TeamId] = s.teamName(channel.TeamId)
+			}
+			item.TeamName = teamNames[channel.TeamId]
+		}
+		response.Votings = append(response.Votings, item)
+	}
+	return response, nil
+}
+
+// searchMemberVotings searches across channels and keeps the votings in
+// channels the user is a member of. Listing all channels of another user
+// takes a permission bots lack, so the membership is checked only for the
+// channels among the results, once per channel. A channel the user is not in
+// is left out of the search that follows, so that its votings can't crowd out
+// the ones the user may see. The channel the user asks from needs no check.
+func (s *VotingService) searchMemberVotings(search repository.VotingSearch, channelID, userID string) ([]model.Voting, error) {
+	members := map[string]bool{channelID: true}
+	for {
+		votings, err := s.VoteRepo.SearchVotings(search)
+		if err != nil {
+			return nil, err
+		}
+		var found []model.Voting
+		excluded := false
+		for _, voting := range votings {
+			member, ok := members[voting.ChannelID]
+			if !ok {
+				member, err = s.isChannelMember(voting.ChannelID, userID)
+				if err != nil {
+					s.PostEphemeralMessage(channelID, userID, "Не удалось проверить ваши каналы, поищите в этом канале без --channel all.")
+					err = errors.UnavailableResource.Wrapf(err, errors.UnavailableResource.Message())
+					err = errors.AddErrorContext(err, userID, "Failed to check channel membership in Mattermost")
+					return nil, err
+				}
+				members[voting.ChannelID] = member
+				if !member {
+					search.ExcludeChannelIDs = append(search.ExcludeChannelIDs, voting.ChannelID)
+					excluded = true
+				}
+			}
+			if member {
+				found = append(found, voting)
+			}
+		}
+		if !excluded {
+			return found, nil
+		}
+	}
+}
+
+// isChannelMember reports whether the user is a member of the channel. A
+// channel the bot has left counts as one the user is not in.
+func (s *VotingService) isChannelMember(channelID, userID string) (bool, error) {
+	_, resp, err := s.Client.GetChannelMember(channelID, userID, "")
+	switch {
+	case resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden):
+		return false, nil
+	case err != nil:
+		s.Logger.Error("Failed to get channel member from Mattermost", slog.String("channel_id", channelID),
+			slog.String("user_id", userID), slog.Any("error", err))
+		return false, err
+	}
+	return true, nil
+}
+
+// channel gets the channel from Mattermost, or nil when it can't.
+func (s *VotingService) channel(channelID string) *mattermodel.Channel {
+	channel, _, err := s.Client.GetChannel(channelID, "")
+	if err != nil {
+		s.Logger.Error("Failed to get channel from Mattermost", slog.String("channel_id", channelID), slog.Any("error", err))
+		return nil
+	}
+	return channel
+}
+
+func (s *VotingService) teamName(teamID string) string {
+	team, _, err := s.Client.GetTeam(teamID, "")
+	if err != nil {
+		s.Logger.Error("Failed to get team from Mattermost", slog.String("team_id", teamID), slog.Any("error", err))
+		return ""
+	}
+	return team.Name
+}
+
+// usernames resolves Mattermost user IDs to usernames. Unknown users keep their ID.
+func (s *VotingService) usernames(userIDs []string) map[string]string {
+	return s.userNames(userIDs, func(user *mattermodel.User) string { return user.Username })
+}
+
+// userNames names users by the given function, one Mattermost request for
+// all of them. IDs that can't be resolved name themselves.
+func (s *VotingService) userNames(userIDs []string, name func(*mattermodel.User) string) map[string]string {
+	names := make(map[string]string, len(userIDs))
+	for _, id := range userIDs {
+		names[id] = id
+	}
+	if len(userIDs) == 0 {
+		return names
+	}
+
+	users, _, err := s.Client.GetUsersByIds(slices.Compact(slices.Sorted(slices.Values(userIDs))))
+	if err != nil {
+		s.Logger.Error("Failed to get users from Mattermost", slog.Any("error", err))
+		return names
+	}
+	for _, user := range users {
+		names[user.Id] = name(user)
+	}
+	return names
+}
+
+// findVoting looks a voting up by its full ID or by a unique prefix of it,
+// such as the short ID shown by /poll list.
+func (s *VotingService) findVoting(ref string) (model.Voting, error) {
+	if utils.IsFullVotingID(ref) || len(ref) < utils.ShortIDLength {
+		return s.VoteRepo.GetVoting(ref)
+	}
+	return s.VoteRepo.FindVotingByPrefix(ref)
+}
+
+func (s *VotingService) GetVoting(votingID string) (model.Voting, error) {
+	return s.VoteRepo.GetVoting(votingID)
+}
+
+// SetVotingPost remembers the post that shows the card of the voting.
+func (s *VotingService) SetVotingPost(votingID, postID string) {
+	if err := s.VoteRepo.SetVotingPost(votingID, postID); err != nil {
+		s.Logger.Error("Failed to save voting post", slog.String("voting_id", votingID),
+			slog.String("post_id", postID), slog.Any("error", err))
+	}
+}
+
+func schulzeResults(voting model.Voting, rankings [][]int) *dto.SchulzeResults {
+	schulze := tally.Schulze(len(voting.Options), rankings)
+	return &dto.SchulzeResults{
+		Pairwise:        schulze.Pairwise,
+		Strongest:       schulze.Strongest,
+		CondorcetWinner: optionLabel(voting, schulze.Condorcet),
+		Winners:         optionLabels(voting, schulze.Winners),
+	}
+}
+
+func scoreResults(voting model.Voting, ballots []model.Ballot) *dto.ScoreResultsResponse {
+	settings := voting.Settings
+	scores := make([][]int, len(voting.Options))
+	for _, ballot := range ballots {
+		for i, option := range ballot.Choices {
+			if option >= 0 && option < len(scores) && i < len(ballot.Scores) {
+				scores[option] = append(scores[option], ballot.Scores[i])
+			}
+		}
+	}
+
+	results := &dto.ScoreResultsResponse{MinScore: settings.MinScore, MaxScore: settings.MaxScore}
+	for option, optionScores := range scores {
+		stats := tally.Scores(settings.MinScore, settings.MaxScore, optionScores)
+		histogram := make([]dto.ScoreCount, len(stats.Histogram))
+		for i, count := range stats.Histogram {
+			histogram[i] = dto.ScoreCount{Score: settings.MinScore + i, Count: count}
+		}
+		results.Options = append(results.Options, dto.OptionScores{
+			Option:    voting.Options[option],
+			Count:     stats.Count,
+			Mean:      stats.Mean,
+			Median:    stats.Median,
+			StdDev:    stats.StdDev,
+			Histogram: histogram,
+		})
+	}
+	return results
+}
+
+func optionLabel(voting model.Voting, option int) string {
+	if option < 0 || option >= len(voting.Options) {
+		return ""
+	}
+	return fmt.Sprintf("%d. %s", option+1, voting.Options[option])
+}
+
+func optionLabels(voting model.Voting, options []int) []string {
+	labels := make([]string, len(options))
+	for i, option := range options {
+		labels[i] = optionLabel(voting, option)
+	}
+	return labels
+}
+
 func (s *VotingService) PostMessage(channelID, message string) {
+	if s.replies.capturePost(channelID, message) {
+		return
+	}
 	post := &mattermodel.Post{
 		ChannelId: channelID,
 		Message:   message,
@@ -212,7 +1067,48 @@ func (s *VotingService) PostMessage(channelID, message string) {
 	}
 }
 
+// PostAttachments posts a message with message attachments, such as vote
+// buttons; without attachments it is a plain message. It returns the ID of
+// the post, empty when posting failed.
+func (s *VotingService) PostAttachments(channelID, message string, attachments []*mattermodel.SlackAttachment) string {
+	post := &mattermodel.Post{
+		ChannelId: channelID,
+		Message:   message,
+	}
+	if len(attachments) > 0 {
+		post.AddProp("attachments", attachments)
+	}
+	created, _, err := s.Client.CreatePost(post)
+	if err != nil {
+		s.Logger.Error("Failed to post message to Mattermost", slog.String("channel_id", channelID), slog.Any("error", err))
+		return ""
+	}
+	return created.Id
+}
+
+// PatchPost replaces the message and the attachments of a post; no
+// attachments take the buttons off it.
+func (s *VotingService) PatchPost(postID, message string, attachments []*mattermodel.SlackAttachment) {
+	if attachments == nil {
+		attachments = []*mattermodel.SlackAttachment{}
+	}
+	props := mattermodel.StringInterface{"attachments": attachments}
+	_, _, err := s.Client.PatchPost(postID, &mattermodel.PostPatch{Message: &message, Props: &props})
+	if err != nil {
+		s.Logger.Error("Failed to patch post in Mattermost", slog.String("post_id", postID), slog.Any("error", err))
+	}
+}
+
 func (s *VotingService) PostEphemeralMessage(channelID, userID, message string) {
+	s.PostEphemeralAttachments(channelID, userID, message, nil)
+}
+
+// PostEphemeralAttachments posts a message only the user sees, with message
+// attachments such as buttons.
+func (s *VotingService) PostEphemeralAttachments(channelID, userID, message string, attachments []*mattermodel.SlackAttachment) {
+	if s.replies.captureEphemeral(channelID, userID, message, attachments) {
+		return
+	}
 	post := &mattermodel.PostEphemeral{
 		UserID: userID,
 		Post: &mattermodel.Post{
@@ -220,6 +1116,9 @@ func (s *VotingService) PostEphemeralMessage(channelID, userID, message string)
 			ChannelId: channelID,
 		},
 	}
+	if len(attachments) > 0 {
+		post.Post.AddProp("attachments", attachments)
+	}
 
 	_, _, err := s.Client.CreatePostEphemeral(post)
 	if err != nil {
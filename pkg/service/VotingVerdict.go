@@ -0,0 +1,131 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/model"
+	"log/slog"
+	"math"
+)
+
+// shareEpsilon absorbs rounding when a share is compared with a quorum or a
+// threshold, so that 2 votes of 3 meet a 2/3 threshold.
+const shareEpsilon = 1e-9
+
+// verdict decides the outcome of a voting with a quorum or a threshold: it
+// passes when the quorum is reached and a single leading option has at least
+// the threshold share of the votes. The quorum of a finished voting is
+// checked against the channel members recorded when it closed; that of an
+// open voting against the members counted now, so its verdict is
+// provisional.
+func (s *VotingService) verdict(voting model.Voting, response dto.VotingResultsResponse) *dto.VerdictResponse {
+	settings := voting.Settings
+	verdict := &dto.VerdictResponse{
+		Final:     voting.IsFinished(),
+		Voters:    response.TotalVoters,
+		Quorum:    settings.Quorum,
+		Threshold: settings.Threshold,
+		Weighted:  settings.Weights != "",
+	}
+
+	// Ranked and score votings have no threshold and elect by their own
+	// tally, so only the quorum decides them.
+	leader, share := -1, 0.0
+	if settings.Kind == model.KindChoice {
+		leader, share = leadingOption(response)
+	}
+	if leader >= 0 {
+		verdict.Leader = response.Results[leader].Option
+		verdict.LeaderShare = share
+	}
+
+	if settings.Quorum > 0 {
+		members := voting.ClosedMembers()
+		// Votings closed without a count fall back to the current members.
+		if !voting.IsFinished() || members == 0 {
+			var err error
+			if members, err = s.channelMemberCount(voting.ChannelID); err != nil {
+				verdict.Outcome = dto.OutcomeUnknown
+				return verdict
+			}
+		}
+		verdict.Members = members
+		verdict.RequiredVoters = int(math.Ceil(settings.Quorum*float64(members) - shareEpsilon))
+		if verdict.Voters < verdict.RequiredVoters {
+			verdict.Outcome = dto.OutcomeNoQuorum
+			return verdict
+		}
+	}
+
+	verdict.Outcome = dto.OutcomePassed
+	if settings.Threshold > 0 && (leader < 0 || share < settings.Threshold-shareEpsilon) {
+		verdict.Outcome = dto.OutcomeFailed
+	}
+	return verdict
+}
+
+// leadingOption finds the option with the most votes, by weight in weighted
+// votings, and its share of the voters or of the total weight. It returns -1
+// when no option leads alone.
+func leadingOption(response dto.VotingResultsResponse) (int, float64) {
+	weighted := response.Weights != ""
+	votes := func(result dto.Result) float64 {
+		if weighted {
+			return result.WeightedVotes
+		}
+		return float64(result.VoteCount)
+	}
+
+	leader, tied := -1, false
+	for i, result := range response.Results {
+		switch {
+		case votes(result) <= 0:
+		case leader < 0 || votes(result) > votes(response.Results[leader]):
+			leader, tied = i, false
+		case votes(result) == votes(response.Results[leader]):
+			tied = true
+		}
+	}
+	if leader < 0 || tied {
+		return -1, 0
+	}
+
+	total := float64(response.TotalVoters)
+	if weighted {
+		total = response.TotalWeight
+	}
+	if total == 0 {
+		return -1, 0
+	}
+	return leader, votes(response.Results[leader]) / total
+}
+
+const channelMembersPerPage = 200
+
+// channelMemberCount asks Mattermost how many members the channel has. Bots,
+// this one included, can't vote and are left out.
+func (s *VotingService) channelMemberCount(channelID string) (int, error) {
+	stats, _, err := s.Client.GetChannelStats(channelID, "")
+	if err != nil {
+		s.Logger.Error("Failed to get channel stats from Mattermost", slog.String("channel_id", channelID), slog.Any("error", err))
+		return 0, err
+	}
+
+	bots := 0
+	for page := 0; ; page++ {
+		users, _, err := s.Client.GetUsersInChannel(channelID, page, channelMembersPerPage, "")
+		if err != nil {
+			s.Logger.Error("Failed to get channel members from Mattermost", slog.String("channel_id", channelID), slog.Any("error", err))
+			return 0, err
+		}
+		for _, user := range users {
+			// Stats count active members only.
+			if user.IsBot && user.DeleteAt == 0 {
+				bots++
+			}
+		}
+		if len(users) < channelMembersPerPage {
+			break
+		}
+	}
+	return int(stats.MemberCount) - bots, nil
+}
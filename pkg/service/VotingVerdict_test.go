@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/model"
+	"maps"
+	"testing"
+	"time"
+)
+
+// channelStats counts 7 members, of them an active bot; the deactivated bot
+// isn't counted by Mattermost either, which leaves 6 voters.
+var channelStats = map[string]string{
+	"/api/v4/channels/channel/stats": `{"channel_id": "channel", "member_count": 7}`,
+	"/api/v4/users": `[{"id": "bot", "is_bot": true}, {"id": "old-bot", "is_bot": true, "delete_at": 1},
+		{"id": "user0"}, {"id": "user1"}, {"id": "user2"}, {"id": "user3"}, {"id": "user4"}, {"id": "user5"}]`,
+}
+
+func TestVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings model.VotingSettings
+		votes    []int
+		outcome  dto.Outcome
+		leader   string
+		required int
+	}{
+		{"quorum not reached", model.VotingSettings{Quorum: 0.5}, []int{0, 0}, dto.OutcomeNoQuorum, "Пицца", 3},
+		{"quorum reached", model.VotingSettings{Quorum: 0.5}, []int{0, 0, 1}, dto.OutcomePassed, "Пицца", 3},
+		// A third of 6 members is 2 voters exactly, while 0.6 of them rounds up.
+		{"quorum of a third", model.VotingSettings{Quorum: 1.0 / 3}, []int{0, 1}, dto.OutcomePassed, "", 2},
+		{"quorum rounded up", model.VotingSettings{Quorum: 0.6}, []int{0, 0, 0}, dto.OutcomeNoQuorum, "Пицца", 4},
+		{"threshold met exactly", model.VotingSettings{Threshold: 2.0 / 3}, []int{0, 0, 1}, dto.OutcomePassed, "Пицца", 0},
+		{"threshold not met", model.VotingSettings{Threshold: 0.75}, []int{0, 0, 1}, dto.OutcomeFailed, "Пицца", 0},
+		{"tie has no leader", model.VotingSettings{Threshold: 0.5}, []int{0, 1}, dto.OutcomeFailed, "", 0},
+		{"quorum and threshold", model.VotingSettings{Quorum: 0.5, Threshold: 0.5}, []int{1, 1, 0, 1}, dto.OutcomePassed, "Суши", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStubbedService(t, channelStats)
+			voting := saveTestVoting(t, s.VoteRepo, model.StateOpen, func(voting *model.Voting) {
+				voting.Settings = tt.settings
+			})
+			for i, option := range tt.votes {
+				if _, err := s.VoteOption(voting.ID, option, voting.ChannelID, fmt.Sprintf("user%d", i)); err != nil {
+					t.Fatalf("vote: %v", err)
+				}
+			}
+			voting, err := s.VoteRepo.GetVoting(voting.ID)
+			if err != nil {
+				t.Fatalf("get voting: %v", err)
+			}
+
+			response, err := s.VotingResults(voting)
+			if err != nil {
+				t.Fatalf("results: %v", err)
+			}
+			verdict := response.Verdict
+			if verdict == nil {
+				t.Fatal("got no verdict")
+			}
+			if verdict.Outcome != tt.outcome || verdict.Leader != tt.leader {
+				t.Errorf("got %s with leader %q, want %s with %q", verdict.Outcome, verdict.Leader, tt.outcome, tt.leader)
+			}
+			if verdict.Voters != len(tt.votes) || verdict.RequiredVoters != tt.required {
+				t.Errorf("got %d voters of %d required, want %d of %d", verdict.Voters, verdict.RequiredVoters, len(tt.votes), tt.required)
+			}
+			if tt.settings.Quorum > 0 && verdict.Members != 6 {
+				t.Errorf("got %d members, want 6 without the bot", verdict.Members)
+			}
+		})
+	}
+}
+
+// Without the channel members the quorum can't be checked, and the voting
+// neither passes nor fails.
+func TestVerdictUnknownWithoutMembers(t *testing.T) {
+	s := newStubbedService(t, nil)
+	voting := saveTestVoting(t, s.VoteRepo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings.Quorum = 0.5
+	})
+	if _, err := s.VoteOption(voting.ID, 0, voting.ChannelID, "user0"); err != nil {
+		t.Fatalf("vote: %v", err)
+	}
+
+	response, err := s.VotingResults(voting)
+	if err != nil {
+		t.Fatalf("results: %v", err)
+	}
+	if verdict := response.Verdict; verdict == nil || verdict.Outcome != dto.OutcomeUnknown || verdict.Members != 0 {
+		t.Errorf("got verdict %+v, want an unknown outcome", verdict)
+	}
+}
+
+// A closed voting keeps to the members it closed with, while an open one is
+// checked against the members counted now.
+func TestVerdictKeepsMembersOfClosing(t *testing.T) {
+	stats := maps.Clone(channelStats)
+	s := newStubbedService(t, stats)
+	voting := saveTestVoting(t, s.VoteRepo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings.Quorum = 0.5
+	})
+	for i := range 3 {
+		if _, err := s.VoteOption(voting.ID, 0, voting.ChannelID, fmt.Sprintf("user%d", i)); err != nil {
+			t.Fatalf("vote: %v", err)
+		}
+	}
+	voting, err := s.VoteRepo.GetVoting(voting.ID)
+	if err != nil {
+		t.Fatalf("get voting: %v", err)
+	}
+	closed, err := s.transition(voting, model.StateClosed, time.Now(), time.Time{})
+	if err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if closed.ClosedMembers() != 6 {
+		t.Fatalf("closed with %d members recorded, want 6", closed.ClosedMembers())
+	}
+
+	// Four more people join the channel after it closed.
+	stats["/api/v4/channels/channel/stats"] = `{"channel_id": "channel", "member_count": 11}`
+
+	response, err := s.VotingResults(closed)
+	if err != nil {
+		t.Fatalf("results: %v", err)
+	}
+	if verdict := response.Verdict; verdict == nil || !verdict.Final || verdict.Outcome != dto.OutcomePassed || verdict.Members != 6 {
+		t.Errorf("closed voting got verdict %+v, want a final pass of 6 members", verdict)
+	}
+
+	reopened, err := s.transition(closed, model.StateOpen, time.Now(), time.Time{})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	response, err = s.VotingResults(reopened)
+	if err != nil {
+		t.Fatalf("results: %v", err)
+	}
+	if verdict := response.Verdict; verdict == nil || verdict.Final || verdict.Outcome != dto.OutcomeNoQuorum || verdict.Members != 10 {
+		t.Errorf("reopened voting got verdict %+v, want a provisional no quorum of 10 members", verdict)
+	}
+}
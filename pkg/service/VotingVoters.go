@@ -0,0 +1,96 @@
+package service
+
+import (
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// VotingVoters lists the voters of a public voting under every option they
+// picked, ranked or scored.
+func (s *VotingService) VotingVoters(request dto.VotingRequest, channelID, userID string) (dto.VotingVotersResponse, error) {
+	votingID := strings.TrimSpace(request.Text)
+	if votingID == "" {
+		s.PostEphemeralMessage(channelID, userID, "Используйте: /poll voters <id голосования>")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "message", "wrong question format, should be /poll voters <voting id>")
+		return dto.VotingVotersResponse{}, err
+	}
+
+	voting, err := s.findVoting(votingID)
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Голосование не найдено.")
+		return dto.VotingVotersResponse{}, err
+	}
+	if err := checkVotingChannel(voting, channelID); err != nil {
+		return dto.VotingVotersResponse{}, err
+	}
+	if !voting.Settings.Public {
+		s.PostEphemeralMessage(channelID, userID, "Это голосование не публичное, список проголосовавших недоступен.")
+		err := errors.BadRequest.New(errors.UnavailableResource.Message())
+		err = errors.AddErrorContext(err, voting.ID, "Voting is not public")
+		return dto.VotingVotersResponse{}, err
+	}
+
+	if err := s.checkResultsAccess(voting, channelID, userID); err != nil {
+		return dto.VotingVotersResponse{}, err
+	}
+
+	ballots, err := s.VoteRepo.GetBallots(voting.ID)
+	if err != nil {
+		return dto.VotingVotersResponse{}, err
+	}
+	userIDs := make([]string, len(ballots))
+	for i, ballot := range ballots {
+		userIDs[i] = ballot.UserID
+	}
+	names := s.displayNames(userIDs)
+
+	response := dto.VotingVotersResponse{
+		VotingID: voting.ID,
+		Question: voting.Question,
+		Options:  make([]dto.OptionVoters, len(voting.Options)),
+		Private:  !voting.ResultsPublic(),
+	}
+	for i := range voting.Options {
+		response.Options[i].Option = optionLabel(voting, i)
+	}
+	for _, ballot := range ballots {
+		for i, option := range ballot.Choices {
+			if option < 0 || option >= len(voting.Options) {
+				continue
+			}
+			voter := dto.Voter{Name: names[ballot.UserID]}
+			switch {
+			case voting.Settings.IsRanked():
+				voter.Rank = i + 1
+			case voting.Settings.IsScore() && i < len(ballot.Scores):
+				voter.Score = &ballot.Scores[i]
+			}
+			response.Options[option].Voters = append(response.Options[option].Voters, voter)
+		}
+	}
+	for _, option := range response.Options {
+		slices.SortStableFunc(option.Voters, func(a, b dto.Voter) int {
+			if a.Rank != b.Rank {
+				return a.Rank - b.Rank
+			}
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+	}
+
+	s.Logger.Info("Voters requested", slog.String("voting_id", voting.ID), slog.String("user_id", userID))
+	return response, nil
+}
+
+// displayNames resolves user IDs to Mattermost display names: the nickname,
+// else the full name, else the username.
+func (s *VotingService) displayNames(userIDs []string) map[string]string {
+	return s.userNames(userIDs, func(user *mattermodel.User) string {
+		return user.GetDisplayName(mattermodel.ShowNicknameFullName)
+	})
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"fmt"
+	"go-voting-bot/pkg/dto"
+	"go-voting-bot/pkg/errors"
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/utils"
+	"log/slog"
+	"slices"
+	"strings"
+
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// SetWeight adds or changes an entry of a channel weight table:
+// "<table> <@username|role:name|group:name> <weight>". Only channel admins
+// may manage weight tables.
+func (s *VotingService) SetWeight(request dto.VotingRequest, channelID, userID string) (model.WeightEntry, error) {
+	args := strings.Fields(request.Text)
+	if len(args) != 3 {
+		s.PostEphemeralMessage(channelID, userID, "Используйте: /poll weights set <таблица> <@пользователь|role:роль|group:группа> <вес>")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "message", "wrong weights format, should be /poll weights set <table> <subject> <weight>")
+		return model.WeightEntry{}, err
+	}
+
+	if !s.isChannelAdmin(channelID, userID) {
+		s.PostEphemeralMessage(channelID, userID, "Таблицы весов могут менять только администраторы канала.")
+		err := errors.BadRequest.New(errors.UnavailableResource.Message())
+		err = errors.AddErrorContext(err, "weights", "Only channel admins can manage weight tables")
+		return model.WeightEntry{}, err
+	}
+
+	weight, err := utils.ParseWeight(args[2])
+	if err != nil {
+		s.PostEphemeralMessage(channelID, userID, "Неверный формат запроса: "+err.Error())
+		err = errors.BadRequest.Wrapf(err, errors.WrongType.Message())
+		err = errors.AddErrorContext(err, "weight", "wrong weight, should be a non-negative number")
+		return model.WeightEntry{}, err
+	}
+
+	entry := model.WeightEntry{ChannelID: channelID, Table: args[0], Weight: weight}
+	subject := args[1]
+	switch {
+	case strings.HasPrefix(subject, "@"):
+		user, _, err := s.Client.GetUserByUsername(strings.TrimPrefix(subject, "@"), "")
+		if err != nil {
+			s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Пользователь %s не найден.", subject))
+			err = errors.NotFound.Wrapf(err, errors.NotFound.Message())
+			err = errors.AddErrorContext(err, subject, "User not found by username")
+			return model.WeightEntry{}, err
+		}
+		entry.Subject, entry.Name = model.WeightUser, user.Id
+	case strings.HasPrefix(subject, "role:") && len(subject) > len("role:"):
+		entry.Subject, entry.Name = model.WeightRole, strings.TrimPrefix(subject, "role:")
+	case strings.HasPrefix(subject, "group:") && len(subject) > len("group:"):
+		entry.Subject, entry.Name = model.WeightGroup, strings.TrimPrefix(subject, "group:")
+	default:
+		s.PostEphemeralMessage(channelID, userID, "Укажите, кому задаётся вес: @пользователь, role:роль или group:группа.")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "subject", "should be @username, role:<name> or group:<name>")
+		return model.WeightEntry{}, err
+	}
+
+	if err := s.VoteRepo.SetWeight(entry); err != nil {
+		return model.WeightEntry{}, err
+	}
+	s.Logger.Info("Weight set", slog.String("channel_id", channelID), slog.String("table", entry.Table),
+		slog.String("subject", string(entry.Subject)), slog.String("name", entry.Name), slog.String("user_id", userID))
+	return entry, nil
+}
+
+// ShowWeights lists the entries of a weight table, or the channel's weight
+// tables when no table is named.
+func (s *VotingService) ShowWeights(request dto.VotingRequest, channelID, userID string) (dto.WeightTableResponse, error) {
+	args := strings.Fields(request.Text)
+	if len(args) > 1 {
+		s.PostEphemeralMessage(channelID, userID, "Используйте: /poll weights show [таблица]")
+		err := errors.BadRequest.New(errors.InvalidFormat.Message())
+		err = errors.AddErrorContext(err, "message", "wrong weights format, should be /poll weights show [table]")
+		return dto.WeightTableResponse{}, err
+	}
+
+	if len(args) == 0 {
+		tables, err := s.VoteRepo.ListWeightTables(channelID)
+		if err != nil {
+			return dto.WeightTableResponse{}, err
+		}
+		return dto.WeightTableResponse{Tables: tables}, nil
+	}
+
+	entries, err := s.weightTable(channelID, userID, args[0])
+	if err != nil {
+		return dto.WeightTableResponse{}, err
+	}
+
+	var userIDs []string
+	for _, entry := range entries {
+		if entry.Subject == model.WeightUser {
+			userIDs = append(userIDs, entry.Name)
+		}
+	}
+	usernames := s.usernames(userIDs)
+
+	response := dto.WeightTableResponse{Table: args[0]}
+	for _, entry := range entries {
+		name := entry.Name
+		if entry.Subject == model.WeightUser {
+			name = usernames[entry.Name]
+		}
+		response.Entries = append(response.Entries, dto.WeightEntry{
+			Subject: string(entry.Subject),
+			Name:    name,
+			Weight:  entry.Weight,
+		})
+	}
+	return response, nil
+}
+
+// weightTable loads a weight table of the channel, which must have entries.
+func (s *VotingService) weightTable(channelID, userID, table string) ([]model.WeightEntry, error) {
+	entries, err := s.VoteRepo.GetWeightTable(channelID, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		s.PostEphemeralMessage(channelID, userID, fmt.Sprintf("Таблица весов «%s» не найдена в этом канале. Создайте её: /poll weights set %s <кому> <вес>", table, table))
+		err := errors.NotFound.New(errors.NotFound.Message())
+		err = errors.AddErrorContext(err, table, "Weight table not found in channel")
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ballotWeight finds what the voter's ballot counts with. An entry for the
+// user wins; otherwise the largest weight of the user's roles and groups
+// applies, and DefaultWeight when nothing matches.
+func (s *VotingService) ballotWeight(voting model.Voting, userID string) (float64, error) {
+	if voting.Settings.Weights == "" {
+		return model.DefaultWeight, nil
+	}
+	entries, err := s.VoteRepo.GetWeightTable(voting.ChannelID, voting.Settings.Weights)
+	if err != nil {
+		return 0, err
+	}
+
+	var roles, groups []string
+	weight, matched := 0.0, false
+	for _, entry := range entries {
+		switch entry.Subject {
+		case model.WeightUser:
+			if entry.Name == userID {
+				return entry.Weight, nil
+			}
+			continue
+		case model.WeightRole:
+			if roles == nil {
+				roles = s.userRoles(voting.ChannelID, userID)
+			}
+			if !slices.Contains(roles, entry.Name) {
+				continue
+			}
+		case model.WeightGroup:
+			if groups == nil {
+				groups = s.userGroups(userID)
+			}
+			if !slices.Contains(groups, entry.Name) {
+				continue
+			}
+		default:
+			continue
+		}
+		if !matched || entry.Weight > weight {
+			weight, matched = entry.Weight, true
+		}
+	}
+	if !matched {
+		return model.DefaultWeight, nil
+	}
+	return weight, nil
+}
+
+// userRoles lists the user's system roles and roles in the channel.
+func (s *VotingService) userRoles(channelID, userID string) []string {
+	roles := []string{}
+	if user, _, err := s.Client.GetUser(userID, ""); err == nil {
+		roles = append(roles, strings.Fields(user.Roles)...)
+	} else {
+		s.Logger.Error("Failed to get user from Mattermost", slog.String("user_id", userID), slog.Any("error", err))
+	}
+	if member, _, err := s.Client.GetChannelMember(channelID, userID, ""); err == nil {
+		roles = append(roles, strings.Fields(member.Roles)...)
+		if member.SchemeAdmin {
+			roles = append(roles, mattermodel.ChannelAdminRoleId)
+		}
+		if member.SchemeUser {
+			roles = append(roles, mattermodel.ChannelUserRoleId)
+		}
+	} else {
+		s.Logger.Error("Failed to get channel member from Mattermost", slog.String("channel_id", channelID), slog.Any("error", err))
+	}
+	return roles
+}
+
+// userGroups lists the names of the Mattermost groups the user is in.
+func (s *VotingService) userGroups(userID string) []string {
+	names := []string{}
+	groups, _, err := s.Client.GetGroupsByUserId(userID)
+	if err != nil {
+		s.Logger.Error("Failed to get user groups from Mattermost", slog.String("user_id", userID), slog.Any("error", err))
+		return names
+	}
+	for _, group := range groups {
+		if group.Name != nil {
+			names = append(names, *group.Name)
+		}
+	}
+	return names
+}
+
+func (s *VotingService) isChannelAdmin(channelID, userID string) bool {
+	roles := s.userRoles(channelID, userID)
+	return slices.Contains(roles, mattermodel.ChannelAdminRoleId) || slices.Contains(roles, mattermodel.SystemAdminRoleId)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"go-voting-bot/pkg/model"
+	"go-voting-bot/pkg/repository"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mattermodel "github.com/mattermost/mattermost-server/v6/model"
+)
+
+// newStubbedService is a service on the memory repository whose Mattermost
+// answers the API paths with the JSON bodies and any other path with 404.
+func newStubbedService(t *testing.T, responses map[string]string) *VotingService {
+	t.Helper()
+	mattermost := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(mattermost.Close)
+
+	return &VotingService{
+		Client:   mattermodel.NewAPIv4Client(mattermost.URL),
+		VoteRepo: repository.NewMemoryRepository(),
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+// weightedService has a "board" weight table in the channel: the chair
+// weighs 5 by name, board group members 3, channel admins 2 and system
+// users 0.5.
+func weightedService(t *testing.T) *VotingService {
+	t.Helper()
+	s := newStubbedService(t, map[string]string{
+		"/api/v4/users/chair":                    `{"id": "chair", "roles": "system_user"}`,
+		"/api/v4/users/chair/groups":             `[{"name": "board"}]`,
+		"/api/v4/users/member":                   `{"id": "member", "roles": "system_user"}`,
+		"/api/v4/users/member/groups":            `[{"name": "staff"}, {"name": "board"}]`,
+		"/api/v4/users/admin":                    `{"id": "admin", "roles": "system_user"}`,
+		"/api/v4/channels/channel/members/admin": `{"channel_id": "channel", "user_id": "admin", "roles": "", "scheme_admin": true, "scheme_user": true}`,
+	})
+	for _, entry := range []model.WeightEntry{
+		{Subject: model.WeightUser, Name: "chair", Weight: 5},
+		{Subject: model.WeightGroup, Name: "board", Weight: 3},
+		{Subject: model.WeightRole, Name: mattermodel.ChannelAdminRoleId, Weight: 2},
+		{Subject: model.WeightRole, Name: mattermodel.SystemUserRoleId, Weight: 0.5},
+	} {
+		entry.ChannelID, entry.Table = "channel", "board"
+		if err := s.VoteRepo.SetWeight(entry); err != nil {
+			t.Fatalf("set weight: %v", err)
+		}
+	}
+	return s
+}
+
+func TestBallotWeight(t *testing.T) {
+	s := weightedService(t)
+	voting := saveTestVoting(t, s.VoteRepo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings.Weights = "board"
+	})
+
+	tests := []struct {
+		name   string
+		userID string
+		want   float64
+	}{
+		{"user entry wins over a heavier group", "chair", 5},
+		{"largest of role and group", "member", 3},
+		{"channel admin role", "admin", 2},
+		// Mattermost knows nothing of the guest, so no role or group matches.
+		{"no entry matches", "guest", model.DefaultWeight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weight, err := s.ballotWeight(voting, tt.userID)
+			if err != nil {
+				t.Fatalf("ballot weight: %v", err)
+			}
+			if weight != tt.want {
+				t.Errorf("got weight %v, want %v", weight, tt.want)
+			}
+		})
+	}
+
+	unweighted := saveTestVoting(t, s.VoteRepo, model.StateOpen)
+	if weight, err := s.ballotWeight(unweighted, "chair"); err != nil || weight != model.DefaultWeight {
+		t.Errorf("unweighted voting: got %v, %v, want %v", weight, err, model.DefaultWeight)
+	}
+}
+
+func TestWeightedResults(t *testing.T) {
+	s := weightedService(t)
+	voting := saveTestVoting(t, s.VoteRepo, model.StateOpen, func(voting *model.Voting) {
+		voting.Settings.Weights = "board"
+	})
+
+	for userID, option := range map[string]int{"chair": 0, "member": 1, "guest": 1} {
+		if _, err := s.VoteOption(voting.ID, option, voting.ChannelID, userID); err != nil {
+			t.Fatalf("vote of %s: %v", userID, err)
+		}
+	}
+	voting, err := s.VoteRepo.GetVoting(voting.ID)
+	if err != nil {
+		t.Fatalf("get voting: %v", err)
+	}
+
+	response, err := s.VotingResults(voting)
+	if err != nil {
+		t.Fatalf("results: %v", err)
+	}
+	if response.Weights != "board" || response.TotalVoters != 3 || response.TotalWeight != 9 {
+		t.Fatalf("got table %q, %d voters of weight %v, want board, 3 of 9", response.Weights, response.TotalVoters, response.TotalWeight)
+	}
+	// Sushi has more voters, but pizza more weight.
+	want := []struct {
+		votes                int
+		weighted, percentage float64
+	}{{1, 5, 500.0 / 9}, {2, 4, 400.0 / 9}}
+	for i, result := range response.Results {
+		if result.VoteCount != want[i].votes || result.WeightedVotes != want[i].weighted ||
+			math.Abs(result.WeightedPercentage-want[i].percentage) > 1e-9 {
+			t.Errorf("%s: got %d votes weighing %v (%.2f%%), want %d weighing %v (%.2f%%)", result.Option,
+				result.VoteCount, result.WeightedVotes, result.WeightedPercentage, want[i].votes, want[i].weighted, want[i].percentage)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package tally counts the ballots of ranked and score votings.
+package tally
+
+import "slices"
+
+// Round is one count of an instant-runoff tally.
+type Round struct {
+	// Votes holds the ballots counted for each continuing option, by
+	// zero-based option index.
+	Votes map[int]int
+	// Eliminated are the options dropped after this round; empty in the
+	// last round.
+	Eliminated []int
+	// Transfers counts where the ballots of the eliminated options went in
+	// the next round, by option index.
+	Transfers map[int]int
+	// Exhausted counts the ballots of the eliminated options that rank no
+	// continuing option and are no longer counted.
+	Exhausted int
+}
+
+// IRVResult is the outcome of an instant-runoff tally.
+type IRVResult struct {
+	Rounds []Round
+	// Winners holds the winning option, several options on a tie that can't
+	// be broken, or nothing when there were no ballots.
+	Winners []int
+}
+
+// IRV counts rankings of options numbered 0..options-1 by instant-runoff.
+// Every round each ballot counts for its highest ranked continuing option.
+// An option with more than half of the counted ballots wins; otherwise the
+// option with the fewest is eliminated and its ballots move to their next
+// preference. Options without ballots are eliminated together. A tie for
+// last place is broken by the most recent round in which the tied options
+// differed; if they never did, all of them are eliminated at once, or share
+// the win when no other option is left.
+func IRV(options int, rankings [][]int) IRVResult {
+	continuing := make(map[int]bool, options)
+	for option := 0; option < options; option++ {
+		continuing[option] = true
+	}
+
+	var result IRVResult
+	for len(continuing) > 0 {
+		round := Round{Votes: make(map[int]int, len(continuing))}
+		for option := range continuing {
+			round.Votes[option] = 0
+		}
+		counted := 0
+		for _, ranking := range rankings {
+			if option, ok := topChoice(ranking, continuing); ok {
+				round.Votes[option]++
+				counted++
+			}
+		}
+
+		if counted == 0 {
+			result.Rounds = append(result.Rounds, round)
+			return result
+		}
+		for option, votes := range round.Votes {
+			if 2*votes > counted {
+				result.Rounds = append(result.Rounds, round)
+				result.Winners = []int{option}
+				return result
+			}
+		}
+
+		eliminated := lastPlace(round.Votes, result.Rounds)
+		if len(eliminated) == len(continuing) {
+			result.Rounds = append(result.Rounds, round)
+			result.Winners = eliminated
+			return result
+		}
+
+		round.Eliminated = eliminated
+		for _, option := range eliminated {
+			delete(continuing, option)
+		}
+		round.Transfers = make(map[int]int)
+		for _, ranking := range rankings {
+			option, ok := topChoice(ranking, round.Votes)
+			if !ok || !slices.Contains(eliminated, option) {
+				continue
+			}
+			if next, ok := topChoice(ranking, continuing); ok {
+				round.Transfers[next]++
+			} else {
+				round.Exhausted++
+			}
+		}
+		result.Rounds = append(result.Rounds, round)
+	}
+	return result
+}
+
+// topChoice finds the highest ranked option present in among.
+func topChoice[V any](ranking []int, among map[int]V) (int, bool) {
+	for _, option := range ranking {
+		if _, ok := among[option]; ok {
+			return option, true
+		}
+	}
+	return 0, false
+}
+
+// lastPlace picks the options to eliminate: every option without votes, or
+// else the one with the fewest, looking back through earlier rounds to
+// break a tie. The result is sorted.
+func lastPlace(votes map[int]int, earlier []Round) []int {
+	fewest := -1
+	for _, count := range votes {
+		if fewest < 0 || count < fewest {
+			fewest = count
+		}
+	}
+	var tied []int
+	for option, count := range votes {
+		if count == fewest {
+			tied = append(tied, option)
+		}
+	}
+	slices.Sort(tied)
+	if fewest == 0 {
+		return tied
+	}
+
+	for i := len(earlier) - 1; i >= 0 && len(tied) > 1; i-- {
+		fewest = -1
+		for _, option := range tied {
+			if count := earlier[i].Votes[option]; fewest < 0 || count < fewest {
+				fewest = count
+			}
+		}
+		tied = slices.DeleteFunc(tied, func(option int) bool {
+			return earlier[i].Votes[option] != fewest
+		})
+	}
+	return tied
+}
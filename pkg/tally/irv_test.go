@@ -0,0 +1,148 @@
+package tally
+
+import (
+	"reflect"
+	"testing"
+)
+
+// repeat returns n copies of ranking.
+func repeat(n int, ranking ...int) [][]int {
+	rankings := make([][]int, n)
+	for i := range rankings {
+		rankings[i] = ranking
+	}
+	return rankings
+}
+
+func concat(groups ...[][]int) [][]int {
+	var rankings [][]int
+	for _, group := range groups {
+		rankings = append(rankings, group...)
+	}
+	return rankings
+}
+
+// The Tennessee capital election: Memphis leads the first count but loses
+// once Chattanooga and Nashville are eliminated.
+func TestIRVTennessee(t *testing.T) {
+	const memphis, nashville, chattanooga, knoxville = 0, 1, 2, 3
+	rankings := concat(
+		repeat(42, memphis, nashville, chattanooga, knoxville),
+		repeat(26, nashville, chattanooga, knoxville, memphis),
+		repeat(15, chattanooga, knoxville, nashville, memphis),
+		repeat(17, knoxville, chattanooga, nashville, memphis),
+	)
+
+	want := IRVResult{
+		Rounds: []Round{
+			{
+				Votes:      map[int]int{memphis: 42, nashville: 26, chattanooga: 15, knoxville: 17},
+				Eliminated: []int{chattanooga},
+				Transfers:  map[int]int{knoxville: 15},
+			},
+			{
+				Votes:      map[int]int{memphis: 42, nashville: 26, knoxville: 32},
+				Eliminated: []int{nashville},
+				Transfers:  map[int]int{knoxville: 26},
+			},
+			{Votes: map[int]int{memphis: 42, knoxville: 58}},
+		},
+		Winners: []int{knoxville},
+	}
+	if got := IRV(4, rankings); !reflect.DeepEqual(got, want) {
+		t.Errorf("IRV gave %+v, want %+v", got, want)
+	}
+}
+
+// A tie for last place is broken by the latest round in which the tied
+// options differed, and ballots without a continuing option are exhausted.
+func TestIRVTieBreakAndExhaustedBallots(t *testing.T) {
+	const a, b, c, d = 0, 1, 2, 3
+	rankings := concat(
+		repeat(5, a),
+		repeat(3, b),
+		repeat(2, c, b),
+		repeat(1, d, c),
+	)
+
+	want := IRVResult{
+		Rounds: []Round{
+			{
+				Votes:      map[int]int{a: 5, b: 3, c: 2, d: 1},
+				Eliminated: []int{d},
+				Transfers:  map[int]int{c: 1},
+			},
+			{
+				// B and C are tied; C had fewer votes in the first round.
+				Votes:      map[int]int{a: 5, b: 3, c: 3},
+				Eliminated: []int{c},
+				Transfers:  map[int]int{b: 2},
+				Exhausted:  1,
+			},
+			{
+				// A and B are tied; B had fewer votes in the second round.
+				Votes:      map[int]int{a: 5, b: 5},
+				Eliminated: []int{b},
+				Transfers:  map[int]int{},
+				Exhausted:  5,
+			},
+			{Votes: map[int]int{a: 5}},
+		},
+		Winners: []int{a},
+	}
+	if got := IRV(4, rankings); !reflect.DeepEqual(got, want) {
+		t.Errorf("IRV gave %+v, want %+v", got, want)
+	}
+}
+
+func TestLastPlace(t *testing.T) {
+	tests := []struct {
+		name    string
+		votes   map[int]int
+		earlier []Round
+		want    []int
+	}{
+		{name: "fewest", votes: map[int]int{0: 4, 1: 2, 2: 3}, want: []int{1}},
+		{name: "without votes together", votes: map[int]int{0: 4, 1: 0, 2: 0}, want: []int{1, 2}},
+		{
+			name:    "tie broken by the latest round",
+			votes:   map[int]int{0: 3, 1: 3, 2: 5},
+			earlier: []Round{{Votes: map[int]int{0: 1, 1: 2}}, {Votes: map[int]int{0: 3, 1: 2}}},
+			want:    []int{1},
+		},
+		{
+			name:    "tie never broken",
+			votes:   map[int]int{0: 3, 1: 3, 2: 5},
+			earlier: []Round{{Votes: map[int]int{0: 2, 1: 2}}},
+			want:    []int{0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastPlace(tt.votes, tt.earlier); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lastPlace gave %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIRVUnbreakableTie(t *testing.T) {
+	got := IRV(3, [][]int{{0}, {1}})
+	want := IRVResult{
+		Rounds: []Round{
+			{Votes: map[int]int{0: 1, 1: 1, 2: 0}, Eliminated: []int{2}, Transfers: map[int]int{}},
+			{Votes: map[int]int{0: 1, 1: 1}},
+		},
+		Winners: []int{0, 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IRV gave %+v, want %+v", got, want)
+	}
+}
+
+func TestIRVWithoutBallots(t *testing.T) {
+	got := IRV(2, nil)
+	if len(got.Winners) != 0 || len(got.Rounds) != 1 {
+		t.Errorf("IRV gave %+v, want one empty round and no winners", got)
+	}
+}
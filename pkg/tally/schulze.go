@@ -0,0 +1,95 @@
+package tally
+
+// SchulzeResult is the outcome of a Schulze tally. Matrices are indexed by
+// zero-based option index, row over column.
+type SchulzeResult struct {
+	// Pairwise[i][j] counts the ballots preferring option i to option j.
+	Pairwise [][]int
+	// Strongest[i][j] is the strength of the strongest path from i to j.
+	Strongest [][]int
+	// Condorcet is the option preferred to every other by a majority, or -1.
+	Condorcet int
+	// Winners holds the options no other option beats by strongest path:
+	// usually one, several on a tie, none when there were no ballots.
+	Winners []int
+}
+
+// Schulze counts rankings of options numbered 0..options-1 by the Schulze
+// method. A ballot prefers every ranked option to every unranked one and
+// has no preference among the options it leaves unranked.
+func Schulze(options int, rankings [][]int) SchulzeResult {
+	result := SchulzeResult{
+		Pairwise:  square(options),
+		Strongest: square(options),
+		Condorcet: -1,
+	}
+
+	for _, ranking := range rankings {
+		position := make([]int, options)
+		for option := range position {
+			position[option] = options
+		}
+		for i := len(ranking) - 1; i >= 0; i-- {
+			if option := ranking[i]; option >= 0 && option < options {
+				position[option] = i
+			}
+		}
+		for i := 0; i < options; i++ {
+			for j := 0; j < options; j++ {
+				if position[i] < position[j] {
+					result.Pairwise[i][j]++
+				}
+			}
+		}
+	}
+
+	d, p := result.Pairwise, result.Strongest
+	for i := 0; i < options; i++ {
+		for j := 0; j < options; j++ {
+			if i != j && d[i][j] > d[j][i] {
+				p[i][j] = d[i][j]
+			}
+		}
+	}
+	for k := 0; k < options; k++ {
+		for i := 0; i < options; i++ {
+			if i == k {
+				continue
+			}
+			for j := 0; j < options; j++ {
+				if j != i && j != k {
+					p[i][j] = max(p[i][j], min(p[i][k], p[k][j]))
+				}
+			}
+		}
+	}
+
+	if len(rankings) == 0 {
+		return result
+	}
+	for i := 0; i < options; i++ {
+		condorcet, winner := true, true
+		for j := 0; j < options; j++ {
+			if i == j {
+				continue
+			}
+			condorcet = condorcet && d[i][j] > d[j][i]
+			winner = winner && p[i][j] >= p[j][i]
+		}
+		if condorcet {
+			result.Condorcet = i
+		}
+		if winner {
+			result.Winners = append(result.Winners, i)
+		}
+	}
+	return result
+}
+
+func square(n int) [][]int {
+	matrix := make([][]int, n)
+	for i := range matrix {
+		matrix[i] = make([]int, n)
+	}
+	return matrix
+}
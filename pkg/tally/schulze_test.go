@@ -0,0 +1,135 @@
+package tally
+
+import (
+	"reflect"
+	"testing"
+)
+
+// A centre squeeze: B is preferred to each other option by a majority, but
+// has the fewest first preferences, so instant runoff eliminates it first.
+func TestSchulzeCondorcetWinnerIRVEliminates(t *testing.T) {
+	const a, b, c = 0, 1, 2
+	rankings := concat(
+		repeat(35, a, b, c),
+		repeat(33, c, b, a),
+		repeat(16, b, a, c),
+		repeat(16, b, c, a),
+	)
+
+	got := Schulze(3, rankings)
+	if got.Condorcet != b || !reflect.DeepEqual(got.Winners, []int{b}) {
+		t.Errorf("Schulze gave Condorcet %d and winners %v, want %d and [%d]", got.Condorcet, got.Winners, b, b)
+	}
+	if irv := IRV(3, rankings); !reflect.DeepEqual(irv.Winners, []int{a}) {
+		t.Errorf("IRV gave winners %v, want [%d]", irv.Winners, a)
+	}
+}
+
+// The example of Schulze's paper as given on Wikipedia: the pairwise
+// majorities form a cycle, and E wins by strongest paths.
+func TestSchulzeBeatpathCycle(t *testing.T) {
+	const a, b, c, d, e = 0, 1, 2, 3, 4
+	rankings := concat(
+		repeat(5, a, c, b, e, d),
+		repeat(5, a, d, e, c, b),
+		repeat(8, b, e, d, a, c),
+		repeat(3, c, a, b, e, d),
+		repeat(7, c, a, e, b, d),
+		repeat(2, c, b, a, d, e),
+		repeat(7, d, c, e, b, a),
+		repeat(8, e, b, a, d, c),
+	)
+
+	want := SchulzeResult{
+		Pairwise: [][]int{
+			{0, 20, 26, 30, 22},
+			{25, 0, 16, 33, 18},
+			{19, 29, 0, 17, 24},
+			{15, 12, 28, 0, 14},
+			{23, 27, 21, 31, 0},
+		},
+		Strongest: [][]int{
+			{0, 28, 28, 30, 24},
+			{25, 0, 28, 33, 24},
+			{25, 29, 0, 29, 24},
+			{25, 28, 28, 0, 24},
+			{25, 28, 28, 31, 0},
+		},
+		Condorcet: -1,
+		Winners:   []int{e},
+	}
+	if got := Schulze(5, rankings); !reflect.DeepEqual(got, want) {
+		t.Errorf("Schulze gave %+v, want %+v", got, want)
+	}
+}
+
+func TestSchulzeTiesAndPartialRankings(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   int
+		rankings  [][]int
+		pairwise  [][]int
+		condorcet int
+		winners   []int
+	}{
+		{
+			name:      "no ballots",
+			options:   2,
+			pairwise:  [][]int{{0, 0}, {0, 0}},
+			condorcet: -1,
+		},
+		{
+			name:      "even split",
+			options:   2,
+			rankings:  [][]int{{0, 1}, {1, 0}},
+			pairwise:  [][]int{{0, 1}, {1, 0}},
+			condorcet: -1,
+			winners:   []int{0, 1},
+		},
+		{
+			name:      "three-way cycle of equal strength",
+			options:   3,
+			rankings:  [][]int{{0, 1, 2}, {1, 2, 0}, {2, 0, 1}},
+			pairwise:  [][]int{{0, 2, 1}, {1, 0, 2}, {2, 1, 0}},
+			condorcet: -1,
+			winners:   []int{0, 1, 2},
+		},
+		{
+			// Ranked options beat the unranked ones, which tie among
+			// themselves: C beats A, and B, tied with both, is unbeaten too.
+			name:      "partial rankings",
+			options:   3,
+			rankings:  [][]int{{0}, {1, 2}, {2}},
+			pairwise:  [][]int{{0, 1, 1}, {1, 0, 1}, {2, 1, 0}},
+			condorcet: -1,
+			winners:   []int{1, 2},
+		},
+		{
+			name:      "partial rankings with a Condorcet winner",
+			options:   3,
+			rankings:  [][]int{{0}, {0, 1}, {2}},
+			pairwise:  [][]int{{0, 2, 2}, {0, 0, 1}, {1, 1, 0}},
+			condorcet: 0,
+			winners:   []int{0},
+		},
+		{
+			name:      "unknown options are ignored",
+			options:   2,
+			rankings:  [][]int{{5, 1}, {-1, 1, 0}},
+			pairwise:  [][]int{{0, 0}, {2, 0}},
+			condorcet: 1,
+			winners:   []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Schulze(tt.options, tt.rankings)
+			if !reflect.DeepEqual(got.Pairwise, tt.pairwise) {
+				t.Errorf("pairwise %v, want %v", got.Pairwise, tt.pairwise)
+			}
+			if got.Condorcet != tt.condorcet || !reflect.DeepEqual(got.Winners, tt.winners) {
+				t.Errorf("Condorcet %d and winners %v, want %d and %v", got.Condorcet, got.Winners, tt.condorcet, tt.winners)
+			}
+		})
+	}
+}
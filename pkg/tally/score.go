@@ -0,0 +1,53 @@
+package tally
+
+import (
+	"math"
+	"slices"
+)
+
+// ScoreStats summarises the scores one option received.
+type ScoreStats struct {
+	Count  int
+	Mean   float64
+	Median float64
+	// StdDev is the population standard deviation.
+	StdDev float64
+	// Histogram[i] counts the scores equal to minScore+i.
+	Histogram []int
+}
+
+// Scores summarises scores given within minScore..maxScore. Scores out of
+// the range are left out of the histogram but not of the statistics.
+func Scores(minScore, maxScore int, scores []int) ScoreStats {
+	stats := ScoreStats{
+		Count:     len(scores),
+		Histogram: make([]int, max(maxScore-minScore+1, 0)),
+	}
+	if len(scores) == 0 {
+		return stats
+	}
+
+	sum := 0
+	for _, score := range scores {
+		sum += score
+		if i := score - minScore; i >= 0 && i < len(stats.Histogram) {
+			stats.Histogram[i]++
+		}
+	}
+	stats.Mean = float64(sum) / float64(len(scores))
+
+	sorted := slices.Sorted(slices.Values(scores))
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		stats.Median = float64(sorted[middle])
+	} else {
+		stats.Median = float64(sorted[middle-1]+sorted[middle]) / 2
+	}
+
+	variance := 0.0
+	for _, score := range scores {
+		variance += (float64(score) - stats.Mean) * (float64(score) - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(len(scores)))
+	return stats
+}
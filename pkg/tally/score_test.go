@@ -0,0 +1,73 @@
+package tally
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestScores(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		scores   []int
+		want     ScoreStats
+	}{
+		{
+			name:   "no scores",
+			min:    1,
+			max:    5,
+			scores: nil,
+			want:   ScoreStats{Histogram: []int{0, 0, 0, 0, 0}},
+		},
+		{
+			name:   "odd count",
+			min:    1,
+			max:    5,
+			scores: []int{5, 1, 4},
+			want:   ScoreStats{Count: 3, Mean: 10.0 / 3, Median: 4, StdDev: math.Sqrt(26.0 / 9), Histogram: []int{1, 0, 0, 1, 1}},
+		},
+		{
+			name:   "even count",
+			min:    1,
+			max:    5,
+			scores: []int{2, 5, 3, 4},
+			want:   ScoreStats{Count: 4, Mean: 3.5, Median: 3.5, StdDev: math.Sqrt(1.25), Histogram: []int{0, 1, 1, 1, 1}},
+		},
+		{
+			name:   "zero to ten",
+			min:    0,
+			max:    10,
+			scores: []int{0, 10, 10, 7},
+			want:   ScoreStats{Count: 4, Mean: 6.75, Median: 8.5, StdDev: math.Sqrt(16.6875), Histogram: []int{1, 0, 0, 0, 0, 0, 0, 1, 0, 0, 2}},
+		},
+		{
+			name:   "negative range",
+			min:    -2,
+			max:    2,
+			scores: []int{-2, -1, -1},
+			want:   ScoreStats{Count: 3, Mean: -4.0 / 3, Median: -1, StdDev: math.Sqrt(2.0 / 9), Histogram: []int{1, 2, 0, 0, 0}},
+		},
+		{
+			// Scores out of the range count in the statistics only.
+			name:   "out of range",
+			min:    1,
+			max:    3,
+			scores: []int{1, 7},
+			want:   ScoreStats{Count: 2, Mean: 4, Median: 4, StdDev: 3, Histogram: []int{1, 0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Scores(tt.min, tt.max, tt.scores)
+			if got.Count != tt.want.Count || !reflect.DeepEqual(got.Histogram, tt.want.Histogram) ||
+				!near(got.Mean, tt.want.Mean) || !near(got.Median, tt.want.Median) || !near(got.StdDev, tt.want.StdDev) {
+				t.Errorf("Scores gave %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
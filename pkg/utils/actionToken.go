@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// ActionToken signs the context of a vote button, so that the action
+// endpoint only takes the buttons the bot has posted.
+func ActionToken(secret []byte, votingID string, option int) string {
+	return sign(secret, votingID, strconv.Itoa(option))
+}
+
+// ValidActionToken reports whether token signs the button context.
+func ValidActionToken(secret []byte, votingID string, option int, token string) bool {
+	return hmac.Equal([]byte(ActionToken(secret, votingID, option)), []byte(token))
+}
+
+// DialogToken signs the state of a poll creation dialog opened for the user
+// in the channel, so that a submission can't be made up for someone else.
+func DialogToken(secret []byte, channelID, userID string) string {
+	return sign(secret, "dialog", channelID, userID)
+}
+
+// ValidDialogToken reports whether token signs the dialog state.
+func ValidDialogToken(secret []byte, channelID, userID, token string) bool {
+	return hmac.Equal([]byte(DialogToken(secret, channelID, userID)), []byte(token))
+}
+
+// sign is the hex HMAC-SHA256 of the parts joined by zero bytes.
+func sign(secret []byte, parts ...string) string {
+	mac := hmac.New(sha256.New, secret)
+	for i, part := range parts {
+		if i > 0 {
+			mac.Write([]byte{0})
+		}
+		mac.Write([]byte(part))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
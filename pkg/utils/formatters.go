@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Plural picks the Russian word form for n: one (1 голос), few (2 голоса) or many (5 голосов).
+func Plural(n int, one, few, many string) string {
+	n %= 100
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n >= 11 && n <= 14:
+		return many
+	case n%10 == 1:
+		return one
+	case n%10 >= 2 && n%10 <= 4:
+		return few
+	default:
+		return many
+	}
+}
+
+func FormatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "только что"
+	case age < time.Hour:
+		return fmt.Sprintf("%d мин. назад", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%d ч. назад", int(age.Hours()))
+	default:
+		days := int(age.Hours() / 24)
+		return fmt.Sprintf("%d %s назад", days, Plural(days, "день", "дня", "дней"))
+	}
+}
+
+var stateNames = map[string]string{
+	"draft":     "черновик",
+	"scheduled": "запланировано",
+	"open":      "открыто",
+	"closed":    "завершено",
+	"archived":  "в архиве",
+}
+
+// FormatState names a voting lifecycle state for users.
+func FormatState(state string) string {
+	if name, ok := stateNames[state]; ok {
+		return name
+	}
+	return state
+}
+
+// ScoreExample is a valid vote in a score voting with the given number of
+// options, such as "5,4,3", for hints.
+func ScoreExample(minScore, maxScore, options int) string {
+	scores := make([]string, options)
+	for i := range scores {
+		scores[i] = fmt.Sprint(maxScore - i%(maxScore-minScore+1))
+	}
+	return strings.Join(scores, ",")
+}
@@ -2,6 +2,21 @@ package utils
 
 import "github.com/google/uuid"
 
+// ShortIDLength is how many leading characters of a voting ID are shown in
+// listings and accepted by commands instead of the full ID.
+const ShortIDLength = 8
+
 func GenerateVotingID() string {
 	return uuid.New().String()
 }
+
+func ShortID(id string) string {
+	if len(id) <= ShortIDLength {
+		return id
+	}
+	return id[:ShortIDLength]
+}
+
+func IsFullVotingID(id string) bool {
+	return uuid.Validate(id) == nil
+}
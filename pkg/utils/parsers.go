@@ -2,7 +2,13 @@ package utils
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 )
 
 func ParseInt(s string) (int, error) {
@@ -16,3 +22,263 @@ func ParseInt(s string) (int, error) {
 	}
 	return value, nil
 }
+
+// ParseChoices reads a comma-separated list of option numbers such as "1,3,4".
+// Every number must be given once.
+func ParseChoices(s string) ([]int, error) {
+	var choices []int
+	for _, part := range strings.Split(s, ",") {
+		choice, err := ParseInt(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(choices, choice) {
+			return nil, fmt.Errorf("вариант %d указан дважды", choice)
+		}
+		choices = append(choices, choice)
+	}
+	return choices, nil
+}
+
+// ParseRanking reads option numbers in order of preference such as "3>1>2".
+// Every number must be given once.
+func ParseRanking(s string) ([]int, error) {
+	var ranking []int
+	for _, part := range strings.Split(s, ">") {
+		option, err := ParseInt(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(ranking, option) {
+			return nil, fmt.Errorf("вариант %d указан дважды", option)
+		}
+		ranking = append(ranking, option)
+	}
+	return ranking, nil
+}
+
+// ParseScores reads comma-separated scores such as "5,3,4"; unlike choices
+// they may repeat.
+func ParseScores(s string) ([]int, error) {
+	var scores []int
+	for _, part := range strings.Split(s, ",") {
+		score, err := ParseInt(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	return scores, nil
+}
+
+// ParseScoreRange reads a range of scores such as "1-5". Scores are not
+// negative and a range holds at most 11 of them, as in "0-10".
+func ParseScoreRange(s string) (int, int, error) {
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("неверный диапазон оценок %q, пример: 1-5", s)
+	}
+	minScore, err := ParseInt(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, fmt.Errorf("неверный диапазон оценок %q, пример: 1-5", s)
+	}
+	maxScore, err := ParseInt(strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, fmt.Errorf("неверный диапазон оценок %q, пример: 1-5", s)
+	}
+	if minScore < 0 || minScore >= maxScore || maxScore-minScore > 10 {
+		return 0, 0, fmt.Errorf("диапазон оценок %q должен быть от меньшей к большей и содержать не больше 11 оценок", s)
+	}
+	return minScore, maxScore, nil
+}
+
+// ParseWeight reads a ballot weight such as "2" or "1.5"; a decimal comma
+// is accepted too. Weights are not negative.
+func ParseWeight(s string) (float64, error) {
+	weight, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return 0, fmt.Errorf("неверный вес %q, пример: 2 или 1.5", s)
+	}
+	if weight < 0 {
+		return 0, fmt.Errorf("вес %q не может быть отрицательным", s)
+	}
+	return weight, nil
+}
+
+// ParseShare reads a share such as "60%", "2/3" or "0.6" as a number above
+// 0 and at most 1.
+func ParseShare(s string) (float64, error) {
+	value := strings.Replace(strings.TrimSpace(s), ",", ".", 1)
+	var share float64
+	var err error
+	if num, den, found := strings.Cut(value, "/"); found {
+		var n, d float64
+		n, err = strconv.ParseFloat(num, 64)
+		if err == nil {
+			d, err = strconv.ParseFloat(den, 64)
+		}
+		if err == nil && d == 0 {
+			err = errors.New("деление на ноль")
+		}
+		share = n / d
+	} else if percent, found := strings.CutSuffix(value, "%"); found {
+		share, err = strconv.ParseFloat(percent, 64)
+		share /= 100
+	} else {
+		share, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil || math.IsNaN(share) {
+		return 0, fmt.Errorf("неверная доля %q, пример: 60%%, 2/3 или 0.6", s)
+	}
+	if share <= 0 || share > 1 {
+		return 0, fmt.Errorf("доля %q должна быть больше нуля и не больше 100%%", s)
+	}
+	return share, nil
+}
+
+// ParseFlags splits command text into "--name value" flags and the remaining
+// text. Flags are read only before and after the text, which is returned
+// verbatim, so a question may hold quotes and dashes of its own. Values may
+// be double-quoted to include spaces; flags listed in boolFlags take no
+// value and are set to "true".
+func ParseFlags(text string, boolFlags ...string) (map[string]string, string, error) {
+	flags := make(map[string]string)
+	rest, err := parseLeadingFlags(strings.TrimSpace(text), flags, boolFlags)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Trailing flags start at the first "--" word after which every word
+	// reads as flags.
+	for i := 1; i < len(rest); i++ {
+		if !strings.HasPrefix(rest[i:], "--") || !unicode.IsSpace(rune(rest[i-1])) {
+			continue
+		}
+		trailing := make(map[string]string)
+		tail, err := parseLeadingFlags(rest[i:], trailing, boolFlags)
+		if err != nil && !strings.Contains(rest[i+2:], " --") {
+			return nil, "", err // the last flag lacks its value
+		}
+		if err != nil || tail != "" {
+			continue
+		}
+		for name, value := range trailing {
+			flags[name] = value
+		}
+		rest = strings.TrimSpace(rest[:i])
+		break
+	}
+	return flags, rest, nil
+}
+
+// parseLeadingFlags reads flags from the start of text into flags and
+// returns the text after them.
+func parseLeadingFlags(text string, flags map[string]string, boolFlags []string) (string, error) {
+	for {
+		word, after, err := cutWord(text)
+		if err != nil || !strings.HasPrefix(word, "--") || len(word) == 2 {
+			return text, nil
+		}
+
+		name := strings.ToLower(strings.TrimPrefix(word, "--"))
+		if slices.Contains(boolFlags, name) {
+			flags[name] = "true"
+			text = after
+			continue
+		}
+		value, after, err := cutWord(after)
+		if err != nil {
+			return "", err
+		}
+		if value == "" {
+			return "", fmt.Errorf("не указано значение для --%s", name)
+		}
+		flags[name] = value
+		text = after
+	}
+}
+
+// timeLayouts are the accepted forms of an absolute time, read in the
+// server's local time zone.
+var timeLayouts = []string{"2006-01-02 15:04", "02.01.2006 15:04"}
+
+// ParseDeadline turns the --duration or --until flag value into the moment a
+// voting should close; a duration counts from start. It returns the zero
+// time when neither is given.
+func ParseDeadline(duration, until string, start time.Time) (time.Time, error) {
+	switch {
+	case duration != "" && until != "":
+		return time.Time{}, errors.New("укажите только один из параметров --duration и --until")
+	case duration != "":
+		d, err := parseDuration(duration)
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("неверная длительность %q, пример: 30m, 2h, 1d12h", duration)
+		}
+		return start.Add(d), nil
+	case until != "":
+		return ParseFutureTime(until, start)
+	}
+	return time.Time{}, nil
+}
+
+// ParseFutureTime parses an absolute time that must come after now. It
+// returns the zero time for an empty value.
+func ParseFutureTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+		if !t.After(now) {
+			return time.Time{}, fmt.Errorf("время %q уже прошло", value)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("неверное время %q, пример: \"2026-11-01 18:00\"", value)
+}
+
+// parseDuration extends time.ParseDuration with a leading number of days, as in "1d12h".
+func parseDuration(s string) (time.Duration, error) {
+	days, rest, found := strings.Cut(s, "d")
+	if !found {
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.Atoi(days)
+	if err != nil || n < 0 {
+		return 0, errors.New("не удалось разобрать число дней")
+	}
+	d := time.Duration(n) * 24 * time.Hour
+	if rest == "" {
+		return d, nil
+	}
+	extra, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, err
+	}
+	return d + extra, nil
+}
+
+// cutWord returns the first word of text, with double-quoted parts taken
+// as they are, and the text after the word without leading spaces.
+func cutWord(text string) (string, string, error) {
+	text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	var word strings.Builder
+	inQuotes := false
+	for i, r := range text {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case unicode.IsSpace(r) && !inQuotes:
+			return word.String(), strings.TrimLeftFunc(text[i:], unicode.IsSpace), nil
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return "", "", errors.New("незакрытая кавычка")
+	}
+	return word.String(), "", nil
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		flags map[string]string
+		rest  string
+		fails bool
+	}{
+		{
+			name:  "no flags",
+			text:  `Обед?  | "Пицца"  | Суши -- или роллы`,
+			flags: map[string]string{},
+			rest:  `Обед?  | "Пицца"  | Суши -- или роллы`,
+		},
+		{
+			name:  "leading",
+			text:  `--ranked --duration 2h Обед? | A | B`,
+			flags: map[string]string{"ranked": "true", "duration": "2h"},
+			rest:  `Обед? | A | B`,
+		},
+		{
+			name:  "trailing",
+			text:  `Обед? | A | B --until "2026-11-01 18:00" --anonymous`,
+			flags: map[string]string{"until": "2026-11-01 18:00", "anonymous": "true"},
+			rest:  `Обед? | A | B`,
+		},
+		{
+			name:  "both",
+			text:  `--draft Кто "лучший" --- по мнению команды? | A | B --max 2`,
+			flags: map[string]string{"draft": "true", "max": "2"},
+			rest:  `Кто "лучший" --- по мнению команды? | A | B`,
+		},
+		{
+			name:  "dashes inside the text",
+			text:  `Включить --verbose по умолчанию? | Да | Нет`,
+			flags: map[string]string{},
+			rest:  `Включить --verbose по умолчанию? | Да | Нет`,
+		},
+		{
+			name:  "only flags",
+			text:  `--closed`,
+			flags: map[string]string{"closed": "true"},
+			rest:  ``,
+		},
+		{name: "missing leading value", text: `--duration`, fails: true},
+		{name: "missing trailing value", text: `Обед? | A | B --duration`, fails: true},
+		{name: "unclosed quote in value", text: `--until "2026-11-01 18:00 Обед? | A | B`, fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, rest, err := ParseFlags(tt.text, "ranked", "draft", "anonymous", "closed")
+			if tt.fails {
+				if err == nil {
+					t.Fatalf("got flags %v and text %q, want an error", flags, rest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if !reflect.DeepEqual(flags, tt.flags) || rest != tt.rest {
+				t.Errorf("got flags %v and text %q, want %v and %q", flags, rest, tt.flags, tt.rest)
+			}
+		})
+	}
+}
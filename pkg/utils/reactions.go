@@ -0,0 +1,12 @@
+package utils
+
+import "slices"
+
+// NumberEmojis are the Mattermost names of the emoji that stand for the
+// options of a voting voted with reactions, in option order.
+var NumberEmojis = []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "keycap_ten"}
+
+// EmojiOption returns the option index a numbered emoji stands for, or -1.
+func EmojiOption(emoji string) int {
+	return slices.Index(NumberEmojis, emoji)
+}
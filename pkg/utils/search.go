@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+const minSearchTermLength = 2
+
+// Tokenize splits text into lower-cased search terms, dropping punctuation,
+// duplicates and terms shorter than two characters.
+func Tokenize(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) < minSearchTermLength || seen[field] {
+			continue
+		}
+		seen[field] = true
+		terms = append(terms, field)
+	}
+	return terms
+}
+
+// SearchTerms returns the indexed terms of a voting with their weights:
+// words of the question count twice as much as words of the options.
+func SearchTerms(question string, options []string) map[string]int {
+	terms := make(map[string]int)
+	for _, term := range Tokenize(question) {
+		terms[term] += 2
+	}
+	for _, option := range options {
+		for _, term := range Tokenize(option) {
+			terms[term]++
+		}
+	}
+	return terms
+}
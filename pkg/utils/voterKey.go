@@ -0,0 +1,9 @@
+package utils
+
+// VoterKey identifies a voter of an anonymous voting without revealing who
+// it is: an HMAC-SHA256 of the voting and user IDs keyed by a server secret.
+// The key differs from voting to voting, so ballots of one user cannot be
+// linked across votings either.
+func VoterKey(secret []byte, votingID, userID string) string {
+	return sign(secret, votingID, userID)
+}